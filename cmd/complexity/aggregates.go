@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// packageStatsType is aggregated statistics of all analyzed functions of a package
+type packageStatsType struct {
+	Package         string
+	Functions       int
+	LOC             int
+	TooComplex      int
+	NotMaintainable int
+	SumCyclo        int
+	MaxCyclo        int
+	MinMaint        int
+	SumMaint        int
+}
+
+// AvgCyclo is average cyclomatic complexity of package functions
+func (p packageStatsType) AvgCyclo() float64 {
+	if p.Functions == 0 {
+		return 0
+	}
+	return float64(p.SumCyclo) / float64(p.Functions)
+}
+
+// AvgMaint is average maintainability index of package functions
+func (p packageStatsType) AvgMaint() float64 {
+	if p.Functions == 0 {
+		return 0
+	}
+	return float64(p.SumMaint) / float64(p.Functions)
+}
+
+// toPackageStats aggregates function stats per package, ordered by package path
+func toPackageStats(arr []complexity.FuncStatsType) []packageStatsType {
+	asMap := map[string]*packageStatsType{}
+	for _, stats := range arr {
+		p, ok := asMap[stats.PackagePath]
+		if !ok {
+			p = &packageStatsType{Package: stats.PackagePath, MinMaint: stats.MaintenabilityIndex}
+			asMap[stats.PackagePath] = p
+		}
+		p.Functions++
+		p.LOC += stats.LOC
+		p.SumCyclo += stats.CyclomaticComplexity
+		p.SumMaint += stats.MaintenabilityIndex
+		if stats.IsTooComplex {
+			p.TooComplex++
+		}
+		if stats.IsNotMaintenable {
+			p.NotMaintainable++
+		}
+		if stats.CyclomaticComplexity > p.MaxCyclo {
+			p.MaxCyclo = stats.CyclomaticComplexity
+		}
+		if stats.MaintenabilityIndex < p.MinMaint {
+			p.MinMaint = stats.MaintenabilityIndex
+		}
+	}
+	arrP := []packageStatsType{}
+	for _, p := range asMap {
+		arrP = append(arrP, *p)
+	}
+	sort.Slice(arrP, func(i, j int) bool { return arrP[i].Package < arrP[j].Package })
+	return arrP
+}
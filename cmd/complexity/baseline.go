@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// baselineViolationType is a crossed threshold identified independently of its position,
+// so that moved functions are matched with their baseline
+type baselineViolationType struct {
+	QualifiedName string
+	Metric        string
+	File          string
+	Line          int
+	Value         int
+}
+
+func (v baselineViolationType) key() string {
+	return v.QualifiedName + "\x00" + v.Metric
+}
+
+// loadBaseline reads a report written earlier with json output format
+func loadBaseline(filename string) (*jsonReportTag, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	report := &jsonReportTag{}
+	if err := json.Unmarshal(buf, report); err != nil {
+		return nil, fmt.Errorf("in baseline %q: %v", filename, err)
+	}
+	if report.SchemaVersion != jsonSchemaVersion {
+		return nil, fmt.Errorf("baseline %q has unsupported schemaVersion %d", filename, report.SchemaVersion)
+	}
+	return report, nil
+}
+
+func baselineViolationsOf(report *jsonReportTag) []baselineViolationType {
+	arr := []baselineViolationType{}
+	for _, f := range report.Functions {
+		if f.IsTooComplex {
+			arr = append(arr, baselineViolationType{QualifiedName: f.QualifiedName, Metric: metricCyclomatic, File: f.File, Line: f.Line, Value: f.CyclomaticComplexity})
+		}
+		if f.IsNotMaintainable {
+			arr = append(arr, baselineViolationType{QualifiedName: f.QualifiedName, Metric: metricMaintainability, File: f.File, Line: f.Line, Value: f.MaintainabilityIndex})
+		}
+	}
+	return arr
+}
+
+func currentViolationsOf(arr []complexity.FuncStatsType) []baselineViolationType {
+	res := []baselineViolationType{}
+	for _, stats := range sortedFuncStats(arr) {
+		for _, v := range violationsOf(stats) {
+			res = append(res, baselineViolationType{
+				QualifiedName: stats.QualifiedName(),
+				Metric:        v.Metric,
+				File:          getRelativeFileName(stats.Filename, currDir),
+				Line:          stats.Line,
+				Value:         v.Value,
+			})
+		}
+	}
+	return res
+}
+
+// compareBaseline returns violations missing in the baseline and baseline violations not found anymore
+func compareBaseline(baseline *jsonReportTag, arr []complexity.FuncStatsType) (newViolations, fixedViolations []baselineViolationType) {
+	before, after := baselineViolationsOf(baseline), currentViolationsOf(arr)
+	beforeKeys, afterKeys := map[string]bool{}, map[string]bool{}
+	for _, v := range before {
+		beforeKeys[v.key()] = true
+	}
+	for _, v := range after {
+		afterKeys[v.key()] = true
+	}
+	newViolations, fixedViolations = []baselineViolationType{}, []baselineViolationType{}
+	for _, v := range after {
+		if !beforeKeys[v.key()] {
+			newViolations = append(newViolations, v)
+		}
+	}
+	for _, v := range before {
+		if !afterKeys[v.key()] {
+			fixedViolations = append(fixedViolations, v)
+		}
+	}
+	sort.SliceStable(fixedViolations, func(i, j int) bool {
+		if fixedViolations[i].File != fixedViolations[j].File {
+			return fixedViolations[i].File < fixedViolations[j].File
+		}
+		return fixedViolations[i].Line < fixedViolations[j].Line
+	})
+	return
+}
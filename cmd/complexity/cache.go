@@ -0,0 +1,249 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+
+	"github.com/fikin/go-complexity-analysis"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// cacheVersion changes whenever cached entries can not be read by older or newer binaries
+const cacheVersion = "1"
+
+// cachedPackageType is the outcome of analyzing a package, as stored in the cache
+type cachedPackageType struct {
+	Stats       []complexity.FuncStatsType `json:"stats"`
+	Diagnostics []cachedDiagnosticType     `json:"diagnostics"`
+}
+
+// cachedDiagnosticType is a diagnostic with its position resolved
+type cachedDiagnosticType struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Category string `json:"category,omitempty"`
+	Message  string `json:"message"`
+}
+
+// defaultCacheDir returns go-complexity under the user cache dir, e.g. $XDG_CACHE_HOME
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-complexity")
+}
+
+// isCacheable tells if the analyzer outcome for a package depends on the package files only,
+// i.e. neither the analyzer nor its requirements use facts of dependencies
+func isCacheable(analyzer *analysis.Analyzer) bool {
+	return !usesFacts(analyzer)
+}
+
+// cacheBuildID identifies the binary by its module and dependencies versions and its build settings, e.g. VCS revision.
+// Development builds, without a version or built from a modified tree, add the executable size and modification time.
+func cacheBuildID() string {
+	id := ""
+	isDevel := true
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		id = bi.String()
+		isDevel = bi.Main.Version == "" || bi.Main.Version == "(devel)"
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.modified" && s.Value == "true" {
+				isDevel = true
+			}
+		}
+	}
+	if isDevel {
+		if exe, err := os.Executable(); err == nil {
+			if fi, err := os.Stat(exe); err == nil {
+				id += fmt.Sprintf("exe %s %d %d\n", exe, fi.Size(), fi.ModTime().UnixNano())
+			}
+		}
+	}
+	return id
+}
+
+// cacheConfigHash hashes everything but the package files the analysis outcome depends on:
+// the binary, the analyzer and the effective configuration
+func cacheConfigHash(analyzer *analysis.Analyzer) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "cache %s\ntool %s\ngo %s\nanalyzer %s\n", cacheVersion, toolVersion(), runtime.Version(), analyzer.Name)
+	fmt.Fprintf(h, "build %s\n", cacheBuildID())
+	fmt.Fprintf(h, "cycloover %d\nmaintunder %d\nlinestats %t\n", complexity.CycloOver, complexity.MaintUnder, complexity.CollectLineStats)
+	cfg, _ := json.Marshal(theConfig)
+	fmt.Fprintf(h, "config %s\n", cfg)
+	if len(skipFiles) > 0 || len(skipDirs) > 0 {
+		fmt.Fprintf(h, "dir %s\n", currDir) // skipped files are matched relative to it
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cachePackageKey hashes the package files names and content.
+// Files are returned in a file set, to resolve cached diagnostics positions with.
+func cachePackageKey(configHash string, pkg *packages.Package) (string, *token.FileSet, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", configHash, pkg.ID, pkg.PkgPath)
+	if pkg.Module != nil {
+		fmt.Fprintf(h, "%s\n", pkg.Module.Path)
+	}
+	fset := token.NewFileSet()
+	for _, fn := range pkg.CompiledGoFiles {
+		buf, err := os.ReadFile(fn)
+		if err != nil {
+			return "", nil, err
+		}
+		fmt.Fprintf(h, "%s %d\n", fn, len(buf))
+		h.Write(buf)
+		fset.AddFile(fn, -1, len(buf)).SetLinesForContent(buf)
+	}
+	return hex.EncodeToString(h.Sum(nil)), fset, nil
+}
+
+func cacheEntryFile(dir string, key string) string {
+	return filepath.Join(dir, key[:2], key+".json")
+}
+
+// getCached returns the cached package outcome, if any
+func getCached(dir string, key string) (*cachedPackageType, bool) {
+	buf, err := os.ReadFile(cacheEntryFile(dir, key))
+	if err != nil {
+		return nil, false
+	}
+	entry := &cachedPackageType{}
+	if err := json.Unmarshal(buf, entry); err != nil {
+		return nil, false
+	}
+	return entry, true
+}
+
+// putCached stores the package outcome, replacing the entry file at once
+func putCached(dir string, key string, entry *cachedPackageType) error {
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	fn := cacheEntryFile(dir, key)
+	if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fn), "tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(buf)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fn)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func toCachedPackage(f foundDiagnosticsStruct) *cachedPackageType {
+	entry := &cachedPackageType{Stats: f.stats, Diagnostics: []cachedDiagnosticType{}}
+	for _, d := range f.diagnostics {
+		pos := f.pkg.Fset.Position(d.Pos)
+		entry.Diagnostics = append(entry.Diagnostics, cachedDiagnosticType{
+			File:     pos.Filename,
+			Line:     pos.Line,
+			Column:   pos.Column,
+			Category: d.Category,
+			Message:  d.Message,
+		})
+	}
+	return entry
+}
+
+// fromCachedPackage restores the package outcome, positions resolved in the package files set
+func fromCachedPackage(pkg *packages.Package, fset *token.FileSet, entry *cachedPackageType) foundDiagnosticsStruct {
+	files := map[string]*token.File{}
+	fset.Iterate(func(f *token.File) bool {
+		files[f.Name()] = f
+		return true
+	})
+	pkg.Fset = fset
+	f := foundDiagnosticsStruct{pkg: pkg, stats: entry.Stats}
+	for _, d := range entry.Diagnostics {
+		pos := token.NoPos
+		if tf, ok := files[d.File]; ok && d.Line > 0 && d.Line <= tf.LineCount() {
+			pos = tf.LineStart(d.Line) + token.Pos(d.Column-1)
+		}
+		f.diagnostics = append(f.diagnostics, analysis.Diagnostic{Pos: pos, Category: d.Category, Message: d.Message})
+	}
+	return f
+}
+
+// analyzeCached loads and analyzes packages not found in the cache only, storing their outcome in it
+func analyzeCached(args []string, listed []*packages.Package, analyzer *analysis.Analyzer, dir string) ([]foundDiagnosticsStruct, error) {
+	configHash := cacheConfigHash(analyzer)
+	found := []foundDiagnosticsStruct{}
+	missed := map[string]string{} // package ID to its key, empty if not to be cached
+	toLoad := []*packages.Package{}
+	for _, pkg := range listed {
+		key, fset, err := cachePackageKey(configHash, pkg)
+		if err == nil {
+			if entry, ok := getCached(dir, key); ok {
+				found = append(found, fromCachedPackage(pkg, fset, entry))
+				continue
+			}
+		}
+		missed[pkg.ID] = key
+		toLoad = append(toLoad, pkg)
+	}
+
+	if len(missed) > 0 {
+		analyzed, err := loadAndAnalyzeListed(args, toLoad, analyzer)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range analyzed {
+			if key := missed[f.pkg.ID]; key != "" && f.err == nil {
+				if err := putCached(dir, key, toCachedPackage(f)); err != nil {
+					log.Printf("caching %s: %v", f.pkg.PkgPath, err)
+				}
+			}
+		}
+		found = append(found, analyzed...)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return lessPackage(found[i].pkg, found[j].pkg) })
+	return found, nil
+}
+
+// cleanCache removes all cached entries
+func cleanCache(dir string) error {
+	if dir == "" {
+		return fmt.Errorf("no cache directory")
+	}
+	return os.RemoveAll(dir)
+}
+
+// runCache implements "complexity cache clean"
+func runCache(args []string, analyzer *analysis.Analyzer) (exitcode int) {
+	if len(args) != 1 || args[0] != "clean" {
+		fmt.Fprintf(os.Stderr, "Usage: %s cache clean\n", analyzer.Name)
+		return 1
+	}
+	if err := cleanCache(defaultCacheDir()); err != nil { // regardless of --no-cache
+		log.Print(err)
+		return 1
+	}
+	return 0
+}
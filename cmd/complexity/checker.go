@@ -3,62 +3,192 @@ package main
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"sync"
+
+	"github.com/fikin/go-complexity-analysis"
 
 	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
 	"golang.org/x/tools/go/packages"
 )
 
-type analyzerResultsType map[*analysis.Analyzer]interface{}
-
+// foundDiagnosticsStruct is the outcome of analyzing a single package
 type foundDiagnosticsStruct struct {
 	pkg         *packages.Package
 	diagnostics []analysis.Diagnostic
+	stats       []complexity.FuncStatsType
 	err         error
 }
 
 func run(args []string, analyzer *analysis.Analyzer) (exitcode int) {
-	pkg, err := load(args)
+	foundDiagnostics, err := loadAndAnalyze(args, analyzer)
 	if err != nil {
 		log.Print(err)
-		return 1 // load errors
+		return 1 // load or driver errors
 	}
 
-	analyzers := deepScanRequires(analyzer)
-
-	foundDiagnostics := analyze(pkg, analyzers)
+	for _, f := range foundDiagnostics {
+		if f.err != nil {
+			log.Printf("%s: %v", f.pkg.PkgPath, f.err)
+		}
+	}
 
 	printDiagnostics(foundDiagnostics)
 
-	if len(foundDiagnostics) > 0 {
-		return 1
+	for _, f := range foundDiagnostics {
+		if f.err != nil || len(f.diagnostics) > 0 {
+			return 1
+		}
 	}
 	return 0
 
 }
 
-// deepScanRequires deep-scans Requires fields and returns the ordered array of analyzers
-func deepScanRequires(analyzer *analysis.Analyzer) []*analysis.Analyzer {
-	if analyzer == nil {
-		return nil
+// funcStatsOf returns stats of all functions of all packages, in packages order
+func funcStatsOf(arr []foundDiagnosticsStruct) []complexity.FuncStatsType {
+	stats := []complexity.FuncStatsType{}
+	for _, f := range arr {
+		stats = append(stats, f.stats...)
+	}
+	return stats
+}
+
+// loadAndAnalyze loads the packages matching args and runs the analyzer over them
+func loadAndAnalyze(args []string, analyzer *analysis.Analyzer) ([]foundDiagnosticsStruct, error) {
+	if syntaxOnly {
+		return parseAndAnalyze(args, analyzer)
+	}
+	if cacheDir != "" && isCacheable(analyzer) {
+		if listed, ok := listPackages(args); ok {
+			return analyzeListed(args, listed, analyzer)
+		}
+	}
+
+	pkg, err := load(args, analyzer)
+	if err != nil {
+		return nil, err
+	}
+
+	return analyze(pkg, []*analysis.Analyzer{analyzer})
+}
+
+// analyzeListed analyzes packages listed by listPackages, through the cache when enabled
+func analyzeListed(args []string, listed []*packages.Package, analyzer *analysis.Analyzer) ([]foundDiagnosticsStruct, error) {
+	if cacheDir != "" && isCacheable(analyzer) {
+		return analyzeCached(args, listed, analyzer, cacheDir)
+	}
+	return loadAndAnalyzeListed(args, listed, analyzer)
+}
+
+// loadAndAnalyzeListed loads packages listed by listPackages and analyzes them, leaving out other loaded packages
+func loadAndAnalyzeListed(args []string, listed []*packages.Package, analyzer *analysis.Analyzer) ([]foundDiagnosticsStruct, error) {
+	ids := map[string]bool{}
+	patterns := []string{}
+	seen := map[string]bool{}
+	for _, pkg := range listed {
+		ids[pkg.ID] = true
+		if pattern := loadPatternOf(pkg); !seen[pattern] {
+			seen[pattern] = true
+			patterns = append(patterns, pattern)
+		}
+	}
+	if seen["command-line-arguments"] { // files given as arguments
+		patterns = args
+	}
+	pkgs, err := load(patterns, analyzer)
+	if err != nil {
+		return nil, err
+	}
+	toAnalyze := []*packages.Package{}
+	for _, pkg := range pkgs {
+		if ids[pkg.ID] {
+			toAnalyze = append(toAnalyze, pkg)
+		}
+	}
+	return analyze(toAnalyze, []*analysis.Analyzer{analyzer})
+}
+
+// loadPatternOf returns the pattern loading the package, test variants are loaded with the package under test
+func loadPatternOf(pkg *packages.Package) string {
+	if pkg.ForTest != "" {
+		return pkg.ForTest
+	}
+	if pkg.Name == "main" && pkg.ID == pkg.PkgPath && strings.HasSuffix(pkg.ID, ".test") { // test binary
+		return strings.TrimSuffix(pkg.ID, ".test")
+	}
+	return pkg.PkgPath
+}
+
+// listPackages lists packages files without parsing or type checking them,
+// false if listing finds any errors, load reports them instead
+func listPackages(args []string) ([]*packages.Package, bool) {
+	conf := newLoadConfig(packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedModule | packages.NeedForTest)
+	listed, err := packages.Load(conf, args...)
+	if err != nil || len(listed) == 0 {
+		return nil, false
+	}
+	for _, pkg := range listed {
+		if len(pkg.Errors) > 0 {
+			return nil, false
+		}
 	}
-	// TODO : strictly speaking append must de-duplicate analyzers i.e. use set instead of array
-	arr := []*analysis.Analyzer{}
-	for _, d := range analyzer.Requires {
-		arr = append(arr, deepScanRequires(d)...)
+	return listed, true
+}
+
+// lessPackage orders packages by path, test variants by ID
+func lessPackage(a *packages.Package, b *packages.Package) bool {
+	if a.PkgPath != b.PkgPath {
+		return a.PkgPath < b.PkgPath
+	}
+	return a.ID < b.ID
+}
+
+// loadModeOf returns the mode loading packages for the analyzer.
+// Analyzers using facts run over dependencies too, their syntax and types are loaded from source then.
+func loadModeOf(analyzer *analysis.Analyzer) packages.LoadMode {
+	// nolint:staticcheck
+	mode := packages.LoadSyntax | packages.NeedModule
+	if usesFacts(analyzer) {
+		mode |= packages.NeedDeps
+	}
+	return mode
+}
+
+// runningDespiteErrors returns a copy of the analyzer run on packages with errors too.
+// Metrics need the syntax only, so they are computed for code not type-checking or not parsing fully.
+func runningDespiteErrors(analyzer *analysis.Analyzer) *analysis.Analyzer {
+	a := *analyzer
+	a.RunDespiteErrors = true
+	return &a
+}
+
+// usesFacts tells if the analyzer or any of its requirements uses facts of dependencies
+func usesFacts(analyzer *analysis.Analyzer) bool {
+	if len(analyzer.FactTypes) > 0 {
+		return true
+	}
+	for _, a := range analyzer.Requires {
+		if usesFacts(a) {
+			return true
+		}
 	}
-	return append(arr, analyzer)
+	return false
 }
 
-// load loads the packages.
-func load(patterns []string) ([]*packages.Package, error) {
-	conf := packages.Config{
-		// nolint:staticcheck
-		Mode:       packages.LoadSyntax,
+// newLoadConfig returns packages configuration of the mode, with tests and build tags as configured
+func newLoadConfig(mode packages.LoadMode) *packages.Config {
+	return &packages.Config{
+		Mode:       mode,
 		Tests:      theConfig.Run.Tests,
 		BuildFlags: formBuildTags(theConfig.Run.BuildTags),
 	}
-	pkgs, err := packages.Load(&conf, patterns...)
+}
+
+// load loads the packages for the analyzer.
+func load(patterns []string, analyzer *analysis.Analyzer) ([]*packages.Package, error) {
+	pkgs, err := packages.Load(newLoadConfig(loadModeOf(analyzer)), patterns...)
 	if err == nil {
 		if n := packages.PrintErrors(pkgs); n > 1 {
 			err = fmt.Errorf("%d errors during loading", n)
@@ -79,54 +209,72 @@ func formBuildTags(buildTags []string) []string {
 	return []string{"--tags", strings.Join(buildTags, ",")}
 }
 
-func analyze(pkgs []*packages.Package, analyzers []*analysis.Analyzer) []foundDiagnosticsStruct {
-	d := []foundDiagnosticsStruct{}
-	for _, pkg := range pkgs {
-		analyzerResults := analyzerResultsType{}
-		for _, a := range analyzers {
-			diags, err := analyzePkg(&analyzerResults, pkg, a)
-			if err != nil || len(diags) > 0 {
-				d = append(d, foundDiagnosticsStruct{pkg: pkg, diagnostics: diags, err: err})
-			}
+// analyze runs the analyzers over packages with the x/tools checker driver.
+// It runs each required analyzer once per package and analyzers using facts over dependencies first.
+// Up to parallelism packages are analyzed at a time, each one by its own sequential driver.
+// Analyzers using facts need all packages in one driver, sharing facts of common dependencies,
+// they analyze packages one at a time.
+// Results are ordered by package path, regardless of the order packages are done in.
+func analyze(pkgs []*packages.Package, analyzers []*analysis.Analyzer) ([]foundDiagnosticsStruct, error) {
+	pkgs = append([]*packages.Package{}, pkgs...)
+	sort.SliceStable(pkgs, func(i, j int) bool { return lessPackage(pkgs[i], pkgs[j]) })
+
+	d := make([]foundDiagnosticsStruct, len(pkgs))
+	index := map[*packages.Package]int{}
+	groups := [][]*packages.Package{} // packages analyzed by the same driver
+	for i, pkg := range pkgs {
+		d[i].pkg = pkg
+		index[pkg] = i
+		groups = append(groups, []*packages.Package{pkg})
+	}
+	for _, a := range analyzers {
+		if usesFacts(a) {
+			groups = [][]*packages.Package{pkgs}
+			break
 		}
 	}
-	return d
-}
-
-func analyzePkg(results *analyzerResultsType, pkg *packages.Package, a *analysis.Analyzer) ([]analysis.Diagnostic, error) {
-	diagnostics := []analysis.Diagnostic{}
-	pass := &analysis.Pass{
-		Analyzer:          a,
-		Fset:              pkg.Fset,
-		Files:             pkg.Syntax,
-		OtherFiles:        pkg.OtherFiles,
-		IgnoredFiles:      pkg.IgnoredFiles,
-		Pkg:               pkg.Types,
-		TypesInfo:         pkg.TypesInfo,
-		TypesSizes:        pkg.TypesSizes,
-		ResultOf:          *results,
-		Report:            func(d analysis.Diagnostic) { diagnostics = append(diagnostics, d) },
-		ImportObjectFact:  nil,
-		ExportObjectFact:  nil,
-		ImportPackageFact: nil,
-		ExportPackageFact: nil,
-		AllObjectFacts:    nil,
-		AllPackageFacts:   nil,
-	}
-	res, err := a.Run(pass)
-	if err == nil {
-		(*results)[a] = res
+
+	errs := make([]error, len(groups))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < parallelism && w < len(groups); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for g := range jobs {
+				errs[g] = analyzeGroup(groups[g], analyzers, d, index)
+			}
+		}()
 	}
-	return diagnostics, err
+	for g := range groups {
+		jobs <- g
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
 }
 
-func doPrintDiagnostics(arr []foundDiagnosticsStruct) {
-	for _, f := range arr {
-		if f.err != nil {
-			fmt.Printf("%s : %v\n", f.pkg.Name, f.err)
+// analyzeGroup runs a sequential driver over the packages, storing the outcome of each one at its index in d
+func analyzeGroup(pkgs []*packages.Package, analyzers []*analysis.Analyzer, d []foundDiagnosticsStruct, index map[*packages.Package]int) error {
+	graph, err := checker.Analyze(analyzers, pkgs, &checker.Options{Sequential: true})
+	if err != nil {
+		return err
+	}
+	for _, act := range graph.Roots {
+		f := &d[index[act.Package]]
+		f.diagnostics = append(f.diagnostics, act.Diagnostics...)
+		if act.Err != nil && f.err == nil {
+			f.err = fmt.Errorf("%s: %w", act.Analyzer.Name, act.Err)
 		}
-		for _, d := range f.diagnostics {
-			fmt.Printf("%s : %d : %s\n", f.pkg.Name, d.Pos, d.Message)
+		if stats, ok := act.Result.([]complexity.FuncStatsType); ok {
+			f.stats = append(f.stats, stats...)
 		}
 	}
+	return nil
 }
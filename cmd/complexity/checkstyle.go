@@ -3,7 +3,10 @@ package main
 import (
 	"encoding/xml"
 	"fmt"
-	"os"
+	"io"
+	"sort"
+
+	"github.com/fikin/go-complexity-analysis"
 )
 
 type checkstyleErrorTag struct {
@@ -15,26 +18,49 @@ type checkstyleErrorTag struct {
 	Source   string   `xml:"source,attr,omitempty"`
 }
 type checkstyleFileTag struct {
-	XMLName  xml.Name `xml:"file"`
-	FileName string   `xml:"name,attr"`
-	Errors   []checkstyleErrorTag
+	XMLName  xml.Name             `xml:"file"`
+	FileName string               `xml:"name,attr"`
+	Errors   []checkstyleErrorTag `xml:"error"`
 }
 
 // checkstyleTag is structure used to serialize in xml all diagnostic
 type checkstyleTag struct {
-	XMLName    xml.Name `xml:"checkstyle"`
-	Version    string   `xml:"version,attr"`
-	filesAsMap map[string]checkstyleFileTag
-	Files      []checkstyleFileTag
+	XMLName xml.Name            `xml:"checkstyle"`
+	Version string              `xml:"version,attr"`
+	Files   []checkstyleFileTag `xml:"file"`
 }
 
-func doPrintcheckstyles(data checkstyleTag) {
-	for _, v := range data.filesAsMap {
-		data.Files = append(data.Files, v)
+// toCheckstyles lists one error per crossed threshold, files and errors ordered by name and position
+func toCheckstyles(arr []complexity.FuncStatsType) checkstyleTag {
+	data := checkstyleTag{Version: "5.0", Files: []checkstyleFileTag{}}
+	fileIndex := map[string]int{}
+	for _, stats := range sortedFuncStats(arr) {
+		for _, v := range violationsOf(stats) {
+			name := getRelativeFileName(stats.Filename, currDir)
+			idx, ok := fileIndex[name]
+			if !ok {
+				idx = len(data.Files)
+				fileIndex[name] = idx
+				data.Files = append(data.Files, checkstyleFileTag{FileName: name, Errors: []checkstyleErrorTag{}})
+			}
+			data.Files[idx].Errors = append(data.Files[idx].Errors, checkstyleErrorTag{
+				Col:      stats.Column,
+				Line:     stats.Line,
+				Msg:      v.Message,
+				Severity: v.Severity,
+				Source:   complexity.Analyzer.Name + "." + v.Metric,
+			})
+		}
 	}
-	output, err := xml.MarshalIndent(data, "  ", "    ")
+	sort.SliceStable(data.Files, func(i, j int) bool { return data.Files[i].FileName < data.Files[j].FileName })
+	return data
+}
+
+func doPrintcheckstyles(w io.Writer, arr []complexity.FuncStatsType) error {
+	output, err := xml.MarshalIndent(toCheckstyles(arr), "", "  ")
 	if err != nil {
-		fmt.Printf("error: %v\n", err)
+		return err
 	}
-	os.Stdout.Write(output)
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, output)
+	return err
 }
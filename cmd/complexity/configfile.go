@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/fikin/go-complexity-analysis"
@@ -14,7 +15,7 @@ import (
 
 var skipFiles []*regexp.Regexp
 var skipDirs []*regexp.Regexp
-var theConfig *ConfigFile
+var theConfig = &ConfigFile{}
 
 // ConfigFile is representing gocomplexity.yml file
 // format is similar to golangci-lint configuration file.
@@ -23,6 +24,16 @@ type ConfigFile struct {
 		Complexity struct {
 			CycloOver  *int `yaml:"cyclo-over,omitempty" json:"cyclo-over,omitempty"`
 			MaintUnder *int `yaml:"maint-under,omitempty" json:"maint-under,omitempty"`
+			Severity   struct {
+				Cyclo []struct {
+					Over     int    `yaml:"over" json:"over"`
+					Severity string `yaml:"severity" json:"severity"`
+				} `yaml:"cyclo,omitempty" json:"cyclo,omitempty"`
+				Maint []struct {
+					Under    int    `yaml:"under" json:"under"`
+					Severity string `yaml:"severity" json:"severity"`
+				} `yaml:"maint,omitempty" json:"maint,omitempty"`
+			} `yaml:"severity" json:"severity"`
 		} `yaml:"complexity" json:"complexity"`
 	} `yaml:"linters-settings" json:"linters-settings"`
 	Run struct {
@@ -74,6 +85,9 @@ func configureConfigIfGiven() (err error) {
 		if theConfig.LintersSettings.Complexity.MaintUnder != nil {
 			complexity.MaintUnder = *theConfig.LintersSettings.Complexity.MaintUnder
 		}
+		if err = configureSeverityTiers(theConfig); err != nil {
+			return err
+		}
 		skipFiles, err = stringArrToRegex(theConfig.Run.SkipFiles)
 		if err != nil {
 			return err
@@ -87,6 +101,33 @@ func configureConfigIfGiven() (err error) {
 	return nil
 }
 
+// configureSeverityTiers replaces default severity tiers with configured ones, if any
+func configureSeverityTiers(c *ConfigFile) error {
+	sev := c.LintersSettings.Complexity.Severity
+	if sev.Cyclo != nil {
+		cycloSeverityTiers = []severityTierType{}
+		for _, t := range sev.Cyclo {
+			cycloSeverityTiers = append(cycloSeverityTiers, severityTierType{Bound: t.Over, Severity: t.Severity})
+		}
+		sort.SliceStable(cycloSeverityTiers, func(i, j int) bool { return cycloSeverityTiers[i].Bound > cycloSeverityTiers[j].Bound })
+	}
+	if sev.Maint != nil {
+		maintSeverityTiers = []severityTierType{}
+		for _, t := range sev.Maint {
+			maintSeverityTiers = append(maintSeverityTiers, severityTierType{Bound: t.Under, Severity: t.Severity})
+		}
+		sort.SliceStable(maintSeverityTiers, func(i, j int) bool { return maintSeverityTiers[i].Bound < maintSeverityTiers[j].Bound })
+	}
+	for _, t := range append(append([]severityTierType{}, cycloSeverityTiers...), maintSeverityTiers...) {
+		switch t.Severity {
+		case severityError, severityWarning, severityInfo:
+		default:
+			return fmt.Errorf("unsupported severity %q, expected one of error, warning, info", t.Severity)
+		}
+	}
+	return nil
+}
+
 func stringArrToRegex(patterns []string) ([]*regexp.Regexp, error) {
 	var patternsRe []*regexp.Regexp
 	for _, p := range patterns {
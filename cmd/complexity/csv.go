@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// csvColumnType is csv column, named after the json report field or, for column, the txt position part
+type csvColumnType struct {
+	Name  string
+	Value func(stats complexity.FuncStatsType) string
+}
+
+func csvInt(v int) string { return strconv.Itoa(v) }
+
+func csvFloat(v float64) string { return fmt.Sprintf("%0.3f", v) }
+
+var csvColumnsAvailable = []csvColumnType{
+	{"file", func(s complexity.FuncStatsType) string { return getRelativeFileName(s.Filename, currDir) }},
+	{"line", func(s complexity.FuncStatsType) string { return csvInt(s.Line) }},
+	{"column", func(s complexity.FuncStatsType) string { return csvInt(s.Column) }},
+	{"endLine", func(s complexity.FuncStatsType) string { return csvInt(s.EndLine) }},
+	{"module", func(s complexity.FuncStatsType) string { return s.ModulePath }},
+	{"package", func(s complexity.FuncStatsType) string { return s.PackagePath }},
+	{"receiver", func(s complexity.FuncStatsType) string { return s.Receiver }},
+	{"name", func(s complexity.FuncStatsType) string { return s.FunctionName }},
+	{"qualifiedName", func(s complexity.FuncStatsType) string { return s.QualifiedName() }},
+	{"cyclomaticComplexity", func(s complexity.FuncStatsType) string { return csvInt(s.CyclomaticComplexity) }},
+	{"cognitiveComplexity", func(s complexity.FuncStatsType) string { return csvInt(s.CognitiveComplexity) }},
+	{"maintainabilityIndex", func(s complexity.FuncStatsType) string { return csvInt(s.MaintenabilityIndex) }},
+	{"halsteadDifficulty", func(s complexity.FuncStatsType) string { return csvFloat(s.HalsbreadDifficulty) }},
+	{"halsteadVolume", func(s complexity.FuncStatsType) string { return csvFloat(s.HalsbreadVolume) }},
+	{"timeToCode", func(s complexity.FuncStatsType) string { return csvFloat(s.TimeToCode) }},
+	{"loc", func(s complexity.FuncStatsType) string { return csvInt(s.LOC) }},
+	{"constantsLoc", func(s complexity.FuncStatsType) string { return csvInt(s.ConstantsLOC) }},
+	{"isTooComplex", func(s complexity.FuncStatsType) string { return strconv.FormatBool(s.IsTooComplex) }},
+	{"isNotMaintainable", func(s complexity.FuncStatsType) string { return strconv.FormatBool(s.IsNotMaintenable) }},
+}
+
+// csvDefaultColumns are the columns printed unless -csv-columns is given
+const csvDefaultColumns = "file,line,name,cyclomaticComplexity,maintainabilityIndex,halsteadDifficulty,halsteadVolume,timeToCode,loc,constantsLoc,isTooComplex,isNotMaintainable"
+
+func csvColumnNamesAvailable() string {
+	names := make([]string, len(csvColumnsAvailable))
+	for i, c := range csvColumnsAvailable {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// parseCSVColumns resolves comma separated column names
+func parseCSVColumns(s string) ([]csvColumnType, error) {
+	cols := []csvColumnType{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, c := range csvColumnsAvailable {
+			if c.Name == name {
+				cols = append(cols, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported csv column %q", name)
+		}
+	}
+	return cols, nil
+}
+
+// doPrintCSV prints header row and functions crossing any threshold, or all of them
+func doPrintCSV(w io.Writer, arr []complexity.FuncStatsType, cols []csvColumnType, all bool) error {
+	cw := csv.NewWriter(w)
+	row := make([]string, len(cols))
+	for i, c := range cols {
+		row[i] = c.Name
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, stats := range sortedFuncStats(arr) {
+		if !all && !stats.IsNotMaintenable && !stats.IsTooComplex {
+			continue
+		}
+		for i, c := range cols {
+			row[i] = c.Value(stats)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
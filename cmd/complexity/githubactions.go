@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// githubSummaryEnv names the file GitHub Actions renders as job summary
+const githubSummaryEnv = "GITHUB_STEP_SUMMARY"
+
+// toGithubCommand maps violation severity onto workflow command name
+func toGithubCommand(severity string) string {
+	switch severity {
+	case severityError:
+		return "error"
+	case severityWarning:
+		return "warning"
+	default:
+		return "notice"
+	}
+}
+
+// escapeGithubData escapes workflow command message, see
+// https://github.com/actions/toolkit/blob/main/packages/core/src/command.ts
+func escapeGithubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGithubProperty escapes workflow command property value
+func escapeGithubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func doPrintGithubActions(w io.Writer, arr []complexity.FuncStatsType) error {
+	for _, stats := range sortedFuncStats(arr) {
+		for _, v := range violationsOf(stats) {
+			_, err := fmt.Fprintf(w, "::%s file=%s,line=%d,endLine=%d,title=%s::%s\n",
+				toGithubCommand(v.Severity),
+				escapeGithubProperty(getRelativeFileName(stats.Filename, currDir)),
+				stats.Line, stats.EndLine,
+				escapeGithubProperty(metrics[metricIndex(v.Metric)].Title),
+				escapeGithubData(v.Message))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func doPrintGithubSummary(w io.Writer, arr []complexity.FuncStatsType) error {
+	rows := []string{}
+	for _, stats := range sortedFuncStats(arr) {
+		for _, v := range violationsOf(stats) {
+			rows = append(rows, fmt.Sprintf("| %s:%d | `%s` | %s | %d | %d |",
+				getRelativeFileName(stats.Filename, currDir), stats.Line,
+				stats.QualifiedName(), metrics[metricIndex(v.Metric)].Title, v.Value, v.Threshold))
+		}
+	}
+	fmt.Fprintf(w, "## Complexity\n\n%d functions analyzed, %d thresholds crossed.\n", len(arr), len(rows))
+	if len(rows) > 0 {
+		fmt.Fprintf(w, "\n| Location | Function | Metric | Value | Threshold |\n|---|---|---|---:|---:|\n%s\n", strings.Join(rows, "\n"))
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// appendGithubSummary appends the markdown summary to the file named by $GITHUB_STEP_SUMMARY
+func appendGithubSummary(arr []complexity.FuncStatsType) error {
+	fn := os.Getenv(githubSummaryEnv)
+	if fn == "" {
+		return fmt.Errorf("job summary requested but $%s is not set", githubSummaryEnv)
+	}
+	f, err := os.OpenFile(fn, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if err = doPrintGithubSummary(f, arr); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
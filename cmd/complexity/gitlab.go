@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+type gitlabLinesTag struct {
+	Begin int `json:"begin"`
+	End   int `json:"end,omitempty"`
+}
+
+type gitlabLocationTag struct {
+	Path  string         `json:"path"`
+	Lines gitlabLinesTag `json:"lines"`
+}
+
+// gitlabIssueTag is single GitLab Code Quality (Code Climate subset) issue
+type gitlabIssueTag struct {
+	Type        string            `json:"type"`
+	Description string            `json:"description"`
+	CheckName   string            `json:"check_name"`
+	Categories  []string          `json:"categories"`
+	Fingerprint string            `json:"fingerprint"`
+	Severity    string            `json:"severity"`
+	Location    gitlabLocationTag `json:"location"`
+}
+
+// toGitlabSeverity maps violation severity onto one of info, minor, major, critical, blocker
+func toGitlabSeverity(severity string) string {
+	switch severity {
+	case severityError:
+		return "major"
+	case severityWarning:
+		return "minor"
+	default:
+		return "info"
+	}
+}
+
+// toGitlabFingerprint identifies the issue by function and metric only,
+// so that moved code is not reported as new issue in merge requests.
+// Functions sharing the name, e.g. several init functions in a package, are told apart by their occurrence, counted from 1.
+func toGitlabFingerprint(stats complexity.FuncStatsType, metric string, occurrence int) string {
+	key := stats.QualifiedName() + "\x00" + metric
+	if occurrence > 1 {
+		key += fmt.Sprintf("\x00%d", occurrence)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
+
+func toGitlabIssues(arr []complexity.FuncStatsType) []gitlabIssueTag {
+	issues := []gitlabIssueTag{}
+	// fingerprints must be unique, GitLab merges issues sharing them
+	seen := map[string]int{}
+	for _, stats := range sortedFuncStats(arr) {
+		name := stats.QualifiedName()
+		seen[name]++
+		for _, v := range violationsOf(stats) {
+			issues = append(issues, gitlabIssueTag{
+				Type:        "issue",
+				Description: v.Message,
+				CheckName:   complexity.Analyzer.Name + "/" + v.Metric,
+				Categories:  []string{"Complexity"},
+				Fingerprint: toGitlabFingerprint(stats, v.Metric, seen[name]),
+				Severity:    toGitlabSeverity(v.Severity),
+				Location: gitlabLocationTag{
+					Path:  filepath.ToSlash(getRelativeFileName(stats.Filename, currDir)),
+					Lines: gitlabLinesTag{Begin: stats.Line, End: stats.EndLine},
+				},
+			})
+		}
+	}
+	return issues
+}
+
+func doPrintGitlab(w io.Writer, arr []complexity.FuncStatsType) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(toGitlabIssues(arr))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+type golangciPositionTag struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+// golangciIssueTag is single issue as of golangci-lint json output
+type golangciIssueTag struct {
+	FromLinter           string
+	Text                 string
+	Severity             string
+	SourceLines          []string
+	Replacement          interface{}
+	Pos                  golangciPositionTag
+	ExpectNoLint         bool
+	ExpectedNoLintLinter string
+}
+
+type golangciLinterTag struct {
+	Name    string
+	Enabled bool
+}
+
+type golangciReportInfoTag struct {
+	Linters []golangciLinterTag
+}
+
+type golangciReportTag struct {
+	Issues []golangciIssueTag
+	Report golangciReportInfoTag
+}
+
+// golangciSourceLine returns the line of the file, reading each file once
+func golangciSourceLine(cache map[string][]string, filename string, line int) []string {
+	lines, ok := cache[filename]
+	if !ok {
+		if buf, err := os.ReadFile(filename); err == nil {
+			lines = strings.Split(string(buf), "\n")
+		}
+		cache[filename] = lines
+	}
+	if line < 1 || line > len(lines) {
+		return nil
+	}
+	return []string{strings.TrimSuffix(lines[line-1], "\r")}
+}
+
+func toGolangciReport(arr []complexity.FuncStatsType) golangciReportTag {
+	report := golangciReportTag{
+		Issues: []golangciIssueTag{},
+		Report: golangciReportInfoTag{Linters: []golangciLinterTag{{Name: complexity.Analyzer.Name, Enabled: true}}},
+	}
+	sources := map[string][]string{}
+	for _, stats := range sortedFuncStats(arr) {
+		for _, v := range violationsOf(stats) {
+			report.Issues = append(report.Issues, golangciIssueTag{
+				FromLinter:  complexity.Analyzer.Name,
+				Text:        v.Message,
+				Severity:    v.Severity,
+				SourceLines: golangciSourceLine(sources, stats.Filename, stats.Line),
+				Pos: golangciPositionTag{
+					Filename: getRelativeFileName(stats.Filename, currDir),
+					Offset:   stats.Offset,
+					Line:     stats.Line,
+					Column:   stats.Column,
+				},
+			})
+		}
+	}
+	return report
+}
+
+func doPrintGolangciJSON(w io.Writer, arr []complexity.FuncStatsType) error {
+	return json.NewEncoder(w).Encode(toGolangciReport(arr))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+//go:embed heatmap.html.tmpl
+var heatmapTemplate string
+
+// heatmapMaxLevel is the darkest shade of a line
+const heatmapMaxLevel = 5
+
+type heatmapLineType struct {
+	Number     int
+	Text       string
+	CycloLevel int
+	HalstLevel int
+	Title      string
+}
+
+type heatmapFileType struct {
+	Index int
+	File  string
+	Lines []heatmapLineType
+}
+
+type heatmapData struct {
+	Files []heatmapFileType
+}
+
+// toHeatmapLevels maps line contribution onto shades, each cyclomatic branch being one shade
+// and every 4 Halstead operators or operands another one.
+func toHeatmapLevels(l complexity.LineStatsType) (cyclo int, halst int) {
+	cyclo, halst = l.Cyclomatic, (l.Operators+l.Operands+3)/4
+	if cyclo > heatmapMaxLevel {
+		cyclo = heatmapMaxLevel
+	}
+	if halst > heatmapMaxLevel {
+		halst = heatmapMaxLevel
+	}
+	return
+}
+
+func toHeatmapData(arr []complexity.FuncStatsType) heatmapData {
+	data := heatmapData{}
+	fileIndex := map[string]int{}
+	for _, stats := range sortedFuncStats(arr) {
+		idx, ok := fileIndex[stats.Filename]
+		if !ok {
+			idx = len(data.Files)
+			fileIndex[stats.Filename] = idx
+			f := heatmapFileType{Index: idx, File: getRelativeFileName(stats.Filename, currDir)}
+			for i, text := range readSourceLines(stats.Filename) {
+				f.Lines = append(f.Lines, heatmapLineType{Number: i + 1, Text: text})
+			}
+			data.Files = append(data.Files, f)
+		}
+		lines := data.Files[idx].Lines
+		for _, l := range stats.LineStats {
+			if l.Line < 1 || l.Line > len(lines) {
+				continue
+			}
+			hl := &lines[l.Line-1]
+			cyclo, halst := toHeatmapLevels(l)
+			hl.CycloLevel, hl.HalstLevel = max(hl.CycloLevel, cyclo), max(hl.HalstLevel, halst)
+			title := fmt.Sprintf("%s: cyclomatic +%d, operators %d, operands %d",
+				stats.FunctionName, l.Cyclomatic, l.Operators, l.Operands)
+			// functions sharing the line list their contributions one per line, test variants repeat them
+			if hl.Title == "" {
+				hl.Title = title
+			} else if !strings.Contains("\n"+hl.Title+"\n", "\n"+title+"\n") {
+				hl.Title += "\n" + title
+			}
+		}
+	}
+	return data
+}
+
+func doPrintHeatmap(w io.Writer, arr []complexity.FuncStatsType) error {
+	tmpl, err := template.New("heatmap").Parse(heatmapTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, toHeatmapData(arr))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+//go:embed report.html.tmpl
+var htmlReportTemplate string
+
+type htmlFunctionRow struct {
+	Package    string
+	Name       string
+	File       string
+	FileIndex  int
+	Line       int
+	EndLine    int
+	Cyclo      int
+	Maint      int
+	Difficulty float64
+	Volume     float64
+	LOC        int
+	Band       string
+}
+
+type htmlBarType struct {
+	Label  string
+	Count  int
+	Height int // percent of the highest bar
+	Band   string
+}
+
+type htmlSourceType struct {
+	Index int
+	File  string
+	Lines []string
+}
+
+type htmlReportData struct {
+	ToolVersion    string
+	GoVersion      string
+	CycloOver      int
+	MaintUnder     int
+	Packages       []packageStatsType
+	Functions      []htmlFunctionRow
+	CycloHistogram []htmlBarType
+	MaintHistogram []htmlBarType
+	Sources        []htmlSourceType
+}
+
+// toHistogram counts values into buckets, bucket i being [bounds[i], bounds[i+1])
+// and the last bucket open-ended.
+func toHistogram(values []int, bounds []int, band func(int) string) []htmlBarType {
+	bars := make([]htmlBarType, len(bounds))
+	for i, b := range bounds {
+		switch {
+		case i == len(bounds)-1:
+			bars[i].Label = fmt.Sprintf("%d+", b)
+		case bounds[i+1]-b == 1:
+			bars[i].Label = fmt.Sprintf("%d", b)
+		default:
+			bars[i].Label = fmt.Sprintf("%d-%d", b, bounds[i+1]-1)
+		}
+		bars[i].Band = band(b)
+	}
+	for _, v := range values {
+		for i := len(bounds) - 1; i >= 0; i-- {
+			if v >= bounds[i] {
+				bars[i].Count++
+				break
+			}
+		}
+	}
+	highest := 0
+	for _, b := range bars {
+		if b.Count > highest {
+			highest = b.Count
+		}
+	}
+	for i := range bars {
+		if highest > 0 {
+			bars[i].Height = bars[i].Count * 100 / highest
+		}
+	}
+	return bars
+}
+
+var (
+	htmlCycloBuckets = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 16, 21, 31}
+	htmlMaintBuckets = []int{0, 10, 20, 30, 40, 50, 60, 70, 80, 90}
+)
+
+// readSourceLines reads the file for the source view, a missing file gives an empty view
+func readSourceLines(filename string) []string {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return []string{fmt.Sprintf("source not available: %v", err)}
+	}
+	return strings.Split(strings.TrimSuffix(string(buf), "\n"), "\n")
+}
+
+func toHTMLReportData(arr []complexity.FuncStatsType) htmlReportData {
+	data := htmlReportData{
+		ToolVersion: toolVersion(),
+		GoVersion:   runtime.Version(),
+		CycloOver:   complexity.CycloOver,
+		MaintUnder:  complexity.MaintUnder,
+		Packages:    toPackageStats(arr),
+		Functions:   []htmlFunctionRow{},
+	}
+	fileIndex := map[string]int{}
+	cyclos, maints := []int{}, []int{}
+	for _, stats := range sortedFuncStats(arr) {
+		idx, ok := fileIndex[stats.Filename]
+		if !ok {
+			idx = len(data.Sources)
+			fileIndex[stats.Filename] = idx
+			data.Sources = append(data.Sources, htmlSourceType{
+				Index: idx,
+				File:  getRelativeFileName(stats.Filename, currDir),
+				Lines: readSourceLines(stats.Filename),
+			})
+		}
+		data.Functions = append(data.Functions, htmlFunctionRow{
+			Package:    stats.PackagePath,
+			Name:       strings.TrimPrefix(stats.QualifiedName(), stats.PackagePath+"."),
+			File:       getRelativeFileName(stats.Filename, currDir),
+			FileIndex:  idx,
+			Line:       stats.Line,
+			EndLine:    stats.EndLine,
+			Cyclo:      stats.CyclomaticComplexity,
+			Maint:      stats.MaintenabilityIndex,
+			Difficulty: stats.HalsbreadDifficulty,
+			Volume:     stats.HalsbreadVolume,
+			LOC:        stats.LOC,
+			Band:       funcBand(stats),
+		})
+		cyclos = append(cyclos, stats.CyclomaticComplexity)
+		maints = append(maints, stats.MaintenabilityIndex)
+	}
+	data.CycloHistogram = toHistogram(cyclos, htmlCycloBuckets, cycloBand)
+	data.MaintHistogram = toHistogram(maints, htmlMaintBuckets, maintBand)
+	return data
+}
+
+var htmlFuncs = template.FuncMap{
+	"cycloBand": cycloBand,
+	"maintBand": maintBand,
+	"inc":       func(i int) int { return i + 1 },
+}
+
+func doPrintHTML(w io.Writer, arr []complexity.FuncStatsType) error {
+	tmpl, err := template.New("report").Funcs(htmlFuncs).Parse(htmlReportTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, toHTMLReportData(arr))
+}
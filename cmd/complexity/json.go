@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"sort"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// jsonSchemaVersion is the version of report.schema.json the json output conforms to.
+// It changes only when existing fields are renamed, removed or change their meaning.
+const jsonSchemaVersion = 1
+
+type jsonThresholdsTag struct {
+	CycloOver  int `json:"cycloOver"`
+	MaintUnder int `json:"maintUnder"`
+}
+
+type jsonToolTag struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type jsonFunctionTag struct {
+	File                 string  `json:"file"`
+	Line                 int     `json:"line"`
+	Column               int     `json:"column"`
+	Offset               int     `json:"offset"`
+	EndLine              int     `json:"endLine"`
+	Module               string  `json:"module,omitempty"`
+	Package              string  `json:"package"`
+	Receiver             string  `json:"receiver,omitempty"`
+	Name                 string  `json:"name"`
+	QualifiedName        string  `json:"qualifiedName"`
+	LOC                  int     `json:"loc"`
+	ConstantsLOC         int     `json:"constantsLoc"`
+	CyclomaticComplexity int     `json:"cyclomaticComplexity"`
+	CognitiveComplexity  int     `json:"cognitiveComplexity"`
+	MaintainabilityIndex int     `json:"maintainabilityIndex"`
+	HalsteadDifficulty   float64 `json:"halsteadDifficulty"`
+	HalsteadVolume       float64 `json:"halsteadVolume"`
+	TimeToCode           float64 `json:"timeToCode"`
+	IsTooComplex         bool    `json:"isTooComplex"`
+	IsNotMaintainable    bool    `json:"isNotMaintainable"`
+}
+
+// jsonReportTag is structure used to serialize in json all function statistics
+type jsonReportTag struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	Tool          jsonToolTag       `json:"tool"`
+	GoVersion     string            `json:"goVersion"`
+	Thresholds    jsonThresholdsTag `json:"thresholds"`
+	Functions     []jsonFunctionTag `json:"functions"`
+}
+
+// toolVersion returns the module version the binary was built from
+func toolVersion() string {
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" {
+		return bi.Main.Version
+	}
+	return "(devel)"
+}
+
+func toJSONReport(arr []complexity.FuncStatsType) jsonReportTag {
+	report := jsonReportTag{
+		SchemaVersion: jsonSchemaVersion,
+		Tool:          jsonToolTag{Name: complexity.Analyzer.Name, Version: toolVersion()},
+		GoVersion:     runtime.Version(),
+		Thresholds:    jsonThresholdsTag{CycloOver: complexity.CycloOver, MaintUnder: complexity.MaintUnder},
+		Functions:     []jsonFunctionTag{},
+	}
+	for _, stats := range sortedFuncStats(arr) {
+		report.Functions = append(report.Functions, jsonFunctionTag{
+			File:                 getRelativeFileName(stats.Filename, currDir),
+			Line:                 stats.Line,
+			Column:               stats.Column,
+			Offset:               stats.Offset,
+			EndLine:              stats.EndLine,
+			Module:               stats.ModulePath,
+			Package:              stats.PackagePath,
+			Receiver:             stats.Receiver,
+			Name:                 stats.FunctionName,
+			QualifiedName:        stats.QualifiedName(),
+			LOC:                  stats.LOC,
+			ConstantsLOC:         stats.ConstantsLOC,
+			CyclomaticComplexity: stats.CyclomaticComplexity,
+			CognitiveComplexity:  stats.CognitiveComplexity,
+			MaintainabilityIndex: stats.MaintenabilityIndex,
+			HalsteadDifficulty:   stats.HalsbreadDifficulty,
+			HalsteadVolume:       stats.HalsbreadVolume,
+			TimeToCode:           stats.TimeToCode,
+			IsTooComplex:         stats.IsTooComplex,
+			IsNotMaintainable:    stats.IsNotMaintenable,
+		})
+	}
+	return report
+}
+
+// sortedFuncStats returns a copy of the stats ordered by file name and line
+func sortedFuncStats(arr []complexity.FuncStatsType) []complexity.FuncStatsType {
+	sorted := append([]complexity.FuncStatsType{}, arr...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Filename != sorted[j].Filename {
+			return sorted[i].Filename < sorted[j].Filename
+		}
+		return sorted[i].Line < sorted[j].Line
+	})
+	return sorted
+}
+
+func doPrintJSON(w io.Writer, arr []complexity.FuncStatsType) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(toJSONReport(arr))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+type junitFailureTag struct {
+	XMLName xml.Name `xml:"failure"`
+	Message string   `xml:"message,attr"`
+	Type    string   `xml:"type,attr"`
+	Text    string   `xml:",chardata"`
+}
+
+type junitTestcaseTag struct {
+	XMLName   xml.Name         `xml:"testcase"`
+	Name      string           `xml:"name,attr"`
+	Classname string           `xml:"classname,attr"`
+	File      string           `xml:"file,attr"`
+	Line      int              `xml:"line,attr"`
+	Time      string           `xml:"time,attr"`
+	Failure   *junitFailureTag `xml:"failure"`
+}
+
+type junitTestsuiteTag struct {
+	XMLName   xml.Name           `xml:"testsuite"`
+	Name      string             `xml:"name,attr"`
+	Tests     int                `xml:"tests,attr"`
+	Failures  int                `xml:"failures,attr"`
+	Errors    int                `xml:"errors,attr"`
+	Time      string             `xml:"time,attr"`
+	Testcases []junitTestcaseTag `xml:"testcase"`
+}
+
+// junitTestsuitesTag is structure used to serialize in JUnit xml all function statistics
+type junitTestsuitesTag struct {
+	XMLName    xml.Name            `xml:"testsuites"`
+	Name       string              `xml:"name,attr"`
+	Tests      int                 `xml:"tests,attr"`
+	Failures   int                 `xml:"failures,attr"`
+	Testsuites []junitTestsuiteTag `xml:"testsuite"`
+}
+
+// toJunitFailure combines all violations of the function into single failure,
+// as not all JUnit consumers accept several failures per testcase.
+func toJunitFailure(stats complexity.FuncStatsType) *junitFailureTag {
+	violations := violationsOf(stats)
+	if len(violations) == 0 {
+		return nil
+	}
+	msgs, types := []string{}, []string{}
+	for _, v := range violations {
+		msgs = append(msgs, v.Message)
+		types = append(types, v.Metric)
+	}
+	return &junitFailureTag{
+		Message: strings.Join(msgs, "; "),
+		Type:    strings.Join(types, ","),
+		Text: fmt.Sprintf("cyclomatic complexity: %d (over %d)\nmaintainability index: %d (under %d)\n"+
+			"halstead difficulty: %0.3f\nhalstead volume: %0.3f\ntime to code: %0.3f\nloc: %d\nconstants loc: %d\n",
+			stats.CyclomaticComplexity, complexity.CycloOver, stats.MaintenabilityIndex, complexity.MaintUnder,
+			stats.HalsbreadDifficulty, stats.HalsbreadVolume, stats.TimeToCode, stats.LOC, stats.ConstantsLOC),
+	}
+}
+
+func toJunitTestsuites(arr []complexity.FuncStatsType) junitTestsuitesTag {
+	suitesAsMap := map[string]*junitTestsuiteTag{}
+	for _, stats := range sortedFuncStats(arr) {
+		suite, ok := suitesAsMap[stats.PackagePath]
+		if !ok {
+			suite = &junitTestsuiteTag{Name: stats.PackagePath, Time: "0"}
+			suitesAsMap[stats.PackagePath] = suite
+		}
+		tc := junitTestcaseTag{
+			Name:      stats.LocalName(),
+			Classname: stats.PackagePath,
+			File:      getRelativeFileName(stats.Filename, currDir),
+			Line:      stats.Line,
+			Time:      "0",
+			Failure:   toJunitFailure(stats),
+		}
+		suite.Tests++
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+		suite.Testcases = append(suite.Testcases, tc)
+	}
+
+	data := junitTestsuitesTag{Name: complexity.Analyzer.Name}
+	for _, suite := range suitesAsMap {
+		data.Tests += suite.Tests
+		data.Failures += suite.Failures
+		data.Testsuites = append(data.Testsuites, *suite)
+	}
+	sort.Slice(data.Testsuites, func(i, j int) bool {
+		return data.Testsuites[i].Name < data.Testsuites[j].Name
+	})
+	return data
+}
+
+func doPrintJunit(w io.Writer, arr []complexity.FuncStatsType) error {
+	output, err := xml.MarshalIndent(toJunitTestsuites(arr), "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if _, err = w.Write(output); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,575 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/fikin/go-complexity-analysis"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// lspDebounce is how long edits are waited for to settle before the file is analyzed again
+const lspDebounce = 300 * time.Millisecond
+
+// JSON-RPC error codes
+const (
+	lspParseError     = -32700
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+)
+
+// LSP diagnostic severities
+var lspSeverities = map[string]int{
+	severityError:   1,
+	severityWarning: 2,
+	severityInfo:    3,
+}
+
+// lspRequestType is an incoming request or, without ID, notification
+type lspRequestType struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type lspResponseType struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+}
+
+type lspErrorResponseType struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   lspErrorTag     `json:"error"`
+}
+
+type lspErrorTag struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspNotificationType struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type lspPositionTag struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRangeTag struct {
+	Start lspPositionTag `json:"start"`
+	End   lspPositionTag `json:"end"`
+}
+
+type lspTextDocumentTag struct {
+	URI  string `json:"uri"`
+	Text string `json:"text,omitempty"`
+}
+
+type lspTextDocumentParamsTag struct {
+	TextDocument lspTextDocumentTag `json:"textDocument"`
+	Position     lspPositionTag     `json:"position"`
+	// full content of didChange, the last one wins as documents are synchronized in full
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges,omitempty"`
+	// content of didSave
+	Text *string `json:"text,omitempty"`
+}
+
+type lspDiagnosticTag struct {
+	Range           lspRangeTag               `json:"range"`
+	Severity        int                       `json:"severity"`
+	Code            string                    `json:"code"`
+	CodeDescription lspDiagnosticCodeDescrTag `json:"codeDescription"`
+	Source          string                    `json:"source"`
+	Message         string                    `json:"message"`
+}
+
+type lspDiagnosticCodeDescrTag struct {
+	Href string `json:"href"`
+}
+
+type lspPublishDiagnosticsTag struct {
+	URI         string             `json:"uri"`
+	Diagnostics []lspDiagnosticTag `json:"diagnostics"`
+}
+
+type lspCodeLensTag struct {
+	Range   lspRangeTag   `json:"range"`
+	Command lspCommandTag `json:"command"`
+}
+
+type lspCommandTag struct {
+	Title   string `json:"title"`
+	Command string `json:"command"`
+}
+
+type lspHoverTag struct {
+	Contents lspMarkupContentTag `json:"contents"`
+	Range    lspRangeTag         `json:"range"`
+}
+
+type lspMarkupContentTag struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// readLSPMessage reads the content of the next base protocol message
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "Content-Length:"); ok {
+			if length, err = strconv.Atoi(strings.TrimSpace(v)); err != nil {
+				return nil, fmt.Errorf("invalid header %q", line)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+	buf := make([]byte, length)
+	_, err := io.ReadFull(r, buf)
+	return buf, err
+}
+
+// writeLSPMessage writes the message with base protocol header
+func writeLSPMessage(w io.Writer, msg interface{}) error {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(buf), buf)
+	return err
+}
+
+// fileURIToPath returns the file name of file:// URI
+func fileURIToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported document URI %s", uri)
+	}
+	path := u.Path
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' { // e.g. /C:/dir on Windows
+		path = path[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(path)), nil
+}
+
+// lspPositionOf converts 1-based line and byte column to the 0-based line and UTF-16 character,
+// column 0 standing for the end of the line
+func lspPositionOf(content []byte, line int, column int) lspPositionTag {
+	text := lineOf(content, line)
+	if column <= 0 || column-1 > len(text) {
+		column = len(text) + 1
+	}
+	return lspPositionTag{Line: line - 1, Character: utf16Len(text[:column-1])}
+}
+
+// lineOf returns the 1-based line of the content, without line ending
+func lineOf(content []byte, line int) []byte {
+	for i := 1; i < line; i++ {
+		n := bytes.IndexByte(content, '\n')
+		if n < 0 {
+			return nil
+		}
+		content = content[n+1:]
+	}
+	if n := bytes.IndexByte(content, '\n'); n >= 0 {
+		content = content[:n]
+	}
+	return bytes.TrimSuffix(content, []byte("\r"))
+}
+
+func utf16Len(b []byte) int {
+	n := 0
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		n += len(utf16.Encode([]rune{r}))
+		b = b[size:]
+	}
+	return n
+}
+
+// lspServerType analyzes packages of files open in the editor, their unsaved buffers overlaying files on disk
+type lspServerType struct {
+	w        io.Writer
+	analyzer *analysis.Analyzer
+	open     map[string][]byte                     // buffers by file name
+	uris     map[string]string                     // document URIs of open files, as the client sent them
+	stats    map[string][]complexity.FuncStatsType // functions of analyzed files by file name
+	pending  map[string]bool                       // open files changed since they were analyzed
+	shutdown bool
+}
+
+func newLSPServer(w io.Writer, analyzer *analysis.Analyzer) *lspServerType {
+	return &lspServerType{
+		w:        w,
+		analyzer: runningDespiteErrors(analyzer), // half-written code in editors
+		open:     map[string][]byte{},
+		uris:     map[string]string{},
+		stats:    map[string][]complexity.FuncStatsType{},
+		pending:  map[string]bool{},
+	}
+}
+
+func (s *lspServerType) reply(msg lspRequestType, result interface{}) error {
+	return writeLSPMessage(s.w, lspResponseType{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *lspServerType) replyError(msg lspRequestType, code int, err error) error {
+	if msg.ID == nil {
+		log.Printf("%s: %v", msg.Method, err)
+		return nil
+	}
+	return writeLSPMessage(s.w, lspErrorResponseType{JSONRPC: "2.0", ID: msg.ID, Error: lspErrorTag{Code: code, Message: err.Error()}})
+}
+
+func (s *lspServerType) notify(method string, params interface{}) error {
+	return writeLSPMessage(s.w, lspNotificationType{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// handle handles the message other than exit, failing only when the client can not be written to
+func (s *lspServerType) handle(msg lspRequestType) error {
+	switch msg.Method {
+	case "initialize":
+		return s.reply(msg, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": map[string]interface{}{"openClose": true, "change": 1, "save": true}, // full content
+				"hoverProvider":    true,
+				"codeLensProvider": map[string]interface{}{"resolveProvider": false},
+			},
+			"serverInfo": map[string]string{"name": s.analyzer.Name, "version": toolVersion()},
+		})
+	case "shutdown":
+		s.shutdown = true
+		return s.reply(msg, nil)
+	case "textDocument/didOpen", "textDocument/didChange", "textDocument/didSave", "textDocument/didClose",
+		"textDocument/codeLens", "textDocument/hover":
+		p := lspTextDocumentParamsTag{}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return s.replyError(msg, lspInvalidParams, err)
+		}
+		fn, err := fileURIToPath(p.TextDocument.URI)
+		if err != nil {
+			return s.replyError(msg, lspInvalidParams, err)
+		}
+		return s.handleDocument(msg, p, fn)
+	}
+	if msg.ID != nil {
+		return s.replyError(msg, lspMethodNotFound, fmt.Errorf("method not supported: %s", msg.Method))
+	}
+	return nil // other notifications, e.g. initialized or $/cancelRequest
+}
+
+func (s *lspServerType) handleDocument(msg lspRequestType, p lspTextDocumentParamsTag, fn string) error {
+	switch msg.Method {
+	case "textDocument/didOpen":
+		s.open[fn] = []byte(p.TextDocument.Text)
+		s.uris[fn] = p.TextDocument.URI
+		s.pending[fn] = true
+	case "textDocument/didChange":
+		if _, ok := s.open[fn]; ok && len(p.ContentChanges) > 0 {
+			s.open[fn] = []byte(p.ContentChanges[len(p.ContentChanges)-1].Text)
+			s.pending[fn] = true
+		}
+	case "textDocument/didSave":
+		if _, ok := s.open[fn]; ok {
+			if p.Text != nil {
+				s.open[fn] = []byte(*p.Text)
+			}
+			s.pending[fn] = true
+		}
+	case "textDocument/didClose":
+		uri, ok := s.uris[fn]
+		if !ok {
+			return nil
+		}
+		delete(s.open, fn)
+		delete(s.uris, fn)
+		delete(s.pending, fn)
+		delete(s.stats, fn)
+		return s.notify("textDocument/publishDiagnostics", lspPublishDiagnosticsTag{URI: uri, Diagnostics: []lspDiagnosticTag{}})
+	case "textDocument/codeLens":
+		if err := s.analyzePending(); err != nil {
+			return err
+		}
+		return s.reply(msg, s.codeLenses(fn))
+	case "textDocument/hover":
+		if err := s.analyzePending(); err != nil {
+			return err
+		}
+		if h, ok := s.hover(fn, p.Position.Line+1); ok {
+			return s.reply(msg, h)
+		}
+		return s.reply(msg, nil)
+	}
+	return nil
+}
+
+// analyzePending analyzes packages of changed open files and publishes diagnostics of all open files in them
+func (s *lspServerType) analyzePending() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	byDir := map[string][]string{} // go list runs in the file's directory, to find its module
+	for fn := range s.pending {
+		byDir[filepath.Dir(fn)] = append(byDir[filepath.Dir(fn)], fn)
+	}
+	analyzed := map[string]bool{}
+	for fn := range s.pending {
+		analyzed[fn] = true // published even if not in any package
+	}
+	s.pending = map[string]bool{}
+
+	dirs := []string{}
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		for _, fn := range s.analyzeDir(dir, byDir[dir]) {
+			analyzed[fn] = true
+		}
+	}
+
+	files := []string{}
+	for fn := range analyzed {
+		if _, ok := s.open[fn]; ok {
+			files = append(files, fn)
+		}
+	}
+	sort.Strings(files)
+	for _, fn := range files {
+		if err := s.notify("textDocument/publishDiagnostics", lspPublishDiagnosticsTag{URI: s.uris[fn], Diagnostics: s.diagnostics(fn)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// analyzeDir loads and analyzes packages of the changed files, returning the files analyzed.
+// Functions of the changed files are forgotten when the packages fail to load, not to publish stale ones.
+func (s *lspServerType) analyzeDir(dir string, changed []string) []string {
+	conf := newLoadConfig(loadModeOf(s.analyzer))
+	conf.Dir = dir
+	conf.Overlay = s.open
+	patterns := []string{}
+	for _, fn := range changed {
+		patterns = append(patterns, "file="+fn)
+	}
+	pkgs, err := packages.Load(conf, patterns...)
+	if err == nil {
+		var found []foundDiagnosticsStruct
+		if found, err = analyze(pkgs, []*analysis.Analyzer{s.analyzer}); err == nil {
+			return s.storeStats(pkgs, found)
+		}
+	}
+	log.Print(err)
+	for _, fn := range changed {
+		delete(s.stats, fn)
+	}
+	return nil
+}
+
+// storeStats replaces functions of the packages' files with the ones found, returning the files
+func (s *lspServerType) storeStats(pkgs []*packages.Package, found []foundDiagnosticsStruct) []string {
+
+	files := []string{}
+	for _, pkg := range pkgs {
+		for _, fn := range pkg.CompiledGoFiles {
+			s.stats[fn] = nil
+			files = append(files, fn)
+		}
+	}
+	seen := map[string]bool{} // test variants of the package share its files
+	for _, f := range found {
+		if f.err != nil {
+			log.Printf("%s: %v", f.pkg.ID, f.err)
+		}
+		for _, st := range f.stats {
+			key := fmt.Sprintf("%s:%d:%d", st.Filename, st.Line, st.Column)
+			if !seen[key] {
+				seen[key] = true
+				s.stats[st.Filename] = append(s.stats[st.Filename], st)
+			}
+		}
+	}
+	return files
+}
+
+// content returns the open buffer of the file, or the file on disk
+func (s *lspServerType) content(fn string) []byte {
+	if buf, ok := s.open[fn]; ok {
+		return buf
+	}
+	buf, _ := os.ReadFile(fn)
+	return buf
+}
+
+// funcLineRange spans the function's first line, from the func keyword on
+func funcLineRange(content []byte, st complexity.FuncStatsType) lspRangeTag {
+	return lspRangeTag{Start: lspPositionOf(content, st.Line, st.Column), End: lspPositionOf(content, st.Line, 0)}
+}
+
+// diagnostics returns the thresholds crossed by functions of the file
+func (s *lspServerType) diagnostics(fn string) []lspDiagnosticTag {
+	content := s.content(fn)
+	arr := []lspDiagnosticTag{}
+	for _, st := range s.stats[fn] {
+		for _, v := range violationsOf(st) {
+			m := metrics[metricIndex(v.Metric)]
+			arr = append(arr, lspDiagnosticTag{
+				Range:           funcLineRange(content, st),
+				Severity:        lspSeverities[v.Severity],
+				Code:            m.RuleName,
+				CodeDescription: lspDiagnosticCodeDescrTag{Href: toMetricHelpURI(m)},
+				Source:          s.analyzer.Name,
+				Message:         v.Message,
+			})
+		}
+	}
+	return arr
+}
+
+// codeLenses returns the metrics summary over each function of the file
+func (s *lspServerType) codeLenses(fn string) []lspCodeLensTag {
+	content := s.content(fn)
+	arr := []lspCodeLensTag{}
+	for _, st := range s.stats[fn] {
+		title := fmt.Sprintf("cyclo %d · MI %d · cognitive %d", st.CyclomaticComplexity, st.MaintenabilityIndex, st.CognitiveComplexity)
+		arr = append(arr, lspCodeLensTag{Range: funcLineRange(content, st), Command: lspCommandTag{Title: title}})
+	}
+	return arr
+}
+
+// hover returns the breakdown of metrics of the function spanning the 1-based line
+func (s *lspServerType) hover(fn string, line int) (lspHoverTag, bool) {
+	for _, st := range s.stats[fn] {
+		if st.Line <= line && line <= st.EndLine {
+			return lspHoverTag{
+				Contents: lspMarkupContentTag{Kind: "markdown", Value: hoverMarkdown(st, line)},
+				Range:    funcLineRange(s.content(fn), st),
+			}, true
+		}
+	}
+	return lspHoverTag{}, false
+}
+
+// hoverMarkdown explains the function metrics, term by term, and what the line contributes to them
+func hoverMarkdown(st complexity.FuncStatsType, line int) string {
+	volTerm, cycloTerm, locTerm := complexity.MaintIndexTerms(st.HalsbreadVolume, st.CyclomaticComplexity, st.LOC)
+	raw := 171 - volTerm - cycloTerm - locTerm
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "**%s**\n\n", st.QualifiedName())
+	fmt.Fprintf(&sb, "Cyclomatic complexity: **%d** (threshold: over %d)\n\n", st.CyclomaticComplexity, complexity.CycloOver)
+	fmt.Fprintf(&sb, "Cognitive complexity: **%d**\n\n", st.CognitiveComplexity)
+	fmt.Fprintf(&sb, "Maintainability index: **%d** (threshold: under %d)\n\n", st.MaintenabilityIndex, complexity.MaintUnder)
+	fmt.Fprintf(&sb, "| term | value |\n|---|---:|\n")
+	fmt.Fprintf(&sb, "| 171 | 171.00 |\n")
+	fmt.Fprintf(&sb, "| − 5.2 · ln(Halstead volume %.2f) | −%.2f |\n", st.HalsbreadVolume, volTerm)
+	fmt.Fprintf(&sb, "| − 0.23 · cyclomatic complexity %d | −%.2f |\n", st.CyclomaticComplexity, cycloTerm)
+	fmt.Fprintf(&sb, "| − 16.2 · ln(lines of code %d) | −%.2f |\n", st.LOC, locTerm)
+	fmt.Fprintf(&sb, "| = | %.2f |\n", raw)
+	fmt.Fprintf(&sb, "| × 100 / 171, at least 0 | %d |\n\n", st.MaintenabilityIndex)
+	fmt.Fprintf(&sb, "Halstead difficulty: %.3f, volume: %.3f, time to code: %.3f h\n\n", st.HalsbreadDifficulty, st.HalsbreadVolume, st.TimeToCode)
+	fmt.Fprintf(&sb, "Lines of code: %d, of them declaring variables: %d", st.LOC, st.ConstantsLOC)
+	for _, l := range st.LineStats {
+		if l.Line == line {
+			fmt.Fprintf(&sb, "\n\nLine %d: cyclomatic +%d, cognitive +%d, %d operators, %d operands", l.Line, l.Cyclomatic, l.Cognitive, l.Operators, l.Operands)
+		}
+	}
+	return sb.String()
+}
+
+// serveLSP serves the client until it sends exit, exit code telling if shutdown was requested first
+func serveLSP(r io.Reader, w io.Writer, analyzer *analysis.Analyzer) (exitcode int) {
+	s := newLSPServer(w, analyzer)
+	msgs := make(chan []byte)
+	errs := make(chan error, 1)
+	go func() {
+		br := bufio.NewReader(r)
+		for {
+			buf, err := readLSPMessage(br)
+			if err != nil {
+				errs <- err
+				return
+			}
+			msgs <- buf
+		}
+	}()
+
+	debounce := time.NewTimer(lspDebounce)
+	debounce.Stop()
+	for {
+		var err error
+		select {
+		case buf := <-msgs:
+			msg := lspRequestType{}
+			if jerr := json.Unmarshal(buf, &msg); jerr != nil {
+				err = s.replyError(lspRequestType{ID: json.RawMessage("null")}, lspParseError, jerr)
+				break
+			}
+			if msg.Method == "exit" {
+				if s.shutdown {
+					return 0
+				}
+				return 1
+			}
+			err = s.handle(msg)
+			if len(s.pending) > 0 {
+				debounce.Reset(lspDebounce)
+			}
+		case <-debounce.C:
+			err = s.analyzePending()
+		case err = <-errs:
+			if err == io.EOF {
+				err = fmt.Errorf("client closed the connection without exit")
+			}
+		}
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+	}
+}
+
+// runLSP implements "complexity lsp", serving the Language Server Protocol over stdin and stdout
+func runLSP(args []string, analyzer *analysis.Analyzer) (exitcode int) {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-flag] lsp\n", analyzer.Name)
+		return 1
+	}
+	complexity.CollectLineStats = true // for hovers
+	return serveLSP(os.Stdin, os.Stdout, analyzer)
+}
@@ -3,28 +3,74 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"text/template"
 
 	"github.com/fikin/go-complexity-analysis"
 	"golang.org/x/tools/go/analysis"
 )
 
 // flag option only in standalone cmdline mode
-// one of : txt, csv, checkstyle
+// comma separated list of format[:file], format one of :
+// txt, csv, checkstyle, json, sarif, junit, github-actions, gitlab, html, heatmap, markdown, openmetrics, sonar, rdjson, rdjsonl, golangci-json
+// or template, the default when -out-template or -out-template-string is given
 var outputFormat = "txt"
 
+// flag option only in standalone cmdline mode
+// when output-format=github-actions, append markdown job summary to $GITHUB_STEP_SUMMARY
+var githubSummary bool
+
+// flag options only in standalone cmdline mode
+// when output-format=markdown, number of worst functions to list,
+// prefix of file links and json report to compare with
+var (
+	markdownTopN     int
+	markdownLinkBase string
+	baselineFile     string
+	baselineReport   *jsonReportTag
+)
+
+// flag options only in standalone cmdline mode
+// text/template to render the output with, read from file or given inline
+var (
+	outTemplateFile   string
+	outTemplateString string
+	outTemplate       *template.Template
+)
+
+// flag options only in standalone cmdline mode
+// when output-format=csv, print all functions instead of only ones crossing thresholds,
+// and which columns
+var (
+	csvAll         bool
+	csvColumnNames string
+	csvColumns     []csvColumnType
+)
+
+// flag option only in standalone cmdline mode
+// number of packages to analyze in parallel
+var parallelism = runtime.GOMAXPROCS(0)
+
+// flag option only in standalone cmdline mode
+// directory of cached analysis results, caching is off if empty
+var cacheDir string
+var noCache bool
+
+// syntaxOnly parses files with go/parser instead of loading type checked packages
+var syntaxOnly bool
+
 // flag option only standalone cmdline mode
 // its format is golangci-lint like yaml configuration
 // subject to limited flags support (see README)
 var configfile string
 
-// gathered function stats to be printed at the end when output-format=csv
-var funcStats = []complexity.FuncStatsType{}
-
-// gathered function stats to be printed at the end when output-format=stylechek
-var checkstyles = checkstyleTag{filesAsMap: map[string]checkstyleFileTag{}, Files: []checkstyleFileTag{}, Version: "5.0"}
+// output formats parsed from output-format, printed at the end
+var outputTargets []outputTargetType
 
 var currDir string
 
@@ -59,18 +105,78 @@ func main() {
 		log.Fatalf("%v", err)
 		os.Exit(1)
 	}
-	configureOutputFormat()
+	if baselineFile != "" {
+		if baselineReport, err = loadBaseline(baselineFile); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	if csvColumns, err = parseCSVColumns(csvColumnNames); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if outTemplateFile != "" || outTemplateString != "" {
+		if outTemplate, err = loadOutTemplate(outTemplateFile, outTemplateString); err != nil {
+			log.Fatalf("%v", err)
+		}
+		if !isFlagGiven("out-format") {
+			outputFormat = "template"
+		}
+	}
+
+	if parallelism < 1 {
+		log.Fatalf("-j must be at least 1, got %d", parallelism)
+	}
+	if markdownTopN < 0 {
+		log.Fatalf("-top must not be negative, got %d", markdownTopN)
+	}
+
+	if !noCache {
+		cacheDir = defaultCacheDir() // the cache sub-command resolves it on its own
+	}
+
+	if syntaxOnly && (args[0] == "watch" || args[0] == "lsp") {
+		log.Fatalf("--syntax-only is not supported by %s", args[0])
+	}
+
+	switch args[0] {
+	case "treemap":
+		os.Exit(runTreemap(args[1:], a))
+	case "cache":
+		os.Exit(runCache(args[1:], a))
+	case "watch":
+		os.Exit(runWatch(args[1:], a))
+	case "lsp":
+		os.Exit(runLSP(args[1:], a))
+	}
+
+	if err := configureOutputFormat(); err != nil {
+		log.Fatalf("%v", err)
+	}
 
 	os.Exit(run(args, a))
 }
 
 func addCmdlineFlags(a *analysis.Analyzer) {
-	flag.StringVar(&outputFormat, "out-format", "txt", "to print the diagnostics as 'csv', 'checkstyle' xml or vet-like 'txt' (default 'txt')")
+	flag.StringVar(&outputFormat, "out-format", "txt", "to print the diagnostics as 'csv', 'checkstyle' xml, 'json', 'sarif', 'junit' xml, 'github-actions' annotations, 'gitlab' code quality, 'html' report, 'heatmap' html of per line contributions, 'markdown' summary, 'openmetrics' gauges, 'sonar' generic issues, reviewdog 'rdjson' or 'rdjsonl', 'golangci-json' report 'template' or vet-like 'txt', as comma separated list of format[:file] to write several formats to files, stdout if no file is given")
+	flag.BoolVar(&githubSummary, "github-summary", false, "with 'github-actions' output format, append markdown job summary to $"+githubSummaryEnv)
+	flag.IntVar(&markdownTopN, "top", 10, "with 'markdown' output format, number of worst functions to list")
+	flag.StringVar(&markdownLinkBase, "link-base", "", "with 'markdown' output format, prefix of file links e.g. https://github.com/org/repo/blob/main/")
+	flag.StringVar(&baselineFile, "baseline", "", "with 'markdown' output format, report earlier written with 'json' output format to list new and fixed violations against")
+	flag.BoolVar(&csvAll, "csv-all", false, "with 'csv' output format, print all functions, not only ones crossing thresholds")
+	flag.StringVar(&csvColumnNames, "csv-columns", csvDefaultColumns, "with 'csv' output format, comma separated columns to print, out of "+csvColumnNamesAvailable())
+	flag.StringVar(&outTemplateFile, "out-template", "", "text/template file to render the output with, instead of -out-format")
+	flag.StringVar(&outTemplateString, "out-template-string", "", "inline text/template to render the output with, instead of -out-format")
+	flag.IntVar(&parallelism, "j", parallelism, "number of packages to analyze in parallel")
 	flag.StringVar(&configfile, "c", "", "configuration like golangci")
+	flag.BoolVar(&noCache, "no-cache", false, "analyze all packages, not reusing nor storing results cached for unchanged packages")
+	flag.BoolVar(&syntaxOnly, "syntax-only", false, "parse files, directories, directory trees like ./... or - for stdin without type checking, analyzing code which does not compile")
 	flag.Usage = func() {
 		paras := strings.Split(a.Doc, "\n\n")
 		fmt.Fprintf(os.Stderr, "%s: %s\n\n", a.Name, paras[0])
-		fmt.Fprintf(os.Stderr, "Usage: %s [-flag] [package]\n\n", a.Name)
+		fmt.Fprintf(os.Stderr, "Usage: %s [-flag] [package]\n", a.Name)
+		fmt.Fprintf(os.Stderr, "       %s [-flag] treemap [-o out.svg] [-color mi|cyclo] [package]\n", a.Name)
+		fmt.Fprintf(os.Stderr, "       %s [-flag] watch [-interval 1s] [package]\n", a.Name)
+		fmt.Fprintf(os.Stderr, "       %s [-flag] lsp\n", a.Name)
+		fmt.Fprintf(os.Stderr, "       %s cache clean\n\n", a.Name)
 		if len(paras) > 1 {
 			fmt.Fprintln(os.Stderr, strings.Join(paras[1:], "\n\n"))
 		}
@@ -79,53 +185,147 @@ func addCmdlineFlags(a *analysis.Analyzer) {
 	}
 }
 
-func configureOutputFormat() {
-	switch outputFormat {
-	case "checkstyle":
-		complexity.FuncStatsCallback = func(stats complexity.FuncStatsType) {
-			msg := complexity.ToDiagnosticMsg(stats)
-			if msg != "" {
-				i, ok := checkstyles.filesAsMap[stats.Filename]
-				if !ok {
-					i = checkstyleFileTag{FileName: getRelativeFileName(stats.Filename, currDir), Errors: []checkstyleErrorTag{}}
-				}
-				i.Errors = append(i.Errors, checkstyleErrorTag{Line: stats.Line, Msg: msg, Severity: "error", Source: "typecheck"})
-				checkstyles.filesAsMap[stats.Filename] = i
+// outputFormatType is a supported output format
+type outputFormatType struct {
+	Name string
+	// LineStats tells the format prints per line contributions, collected only when needed
+	LineStats bool
+	Print     func(w io.Writer, arr []complexity.FuncStatsType, diags []foundDiagnosticsStruct) error
+}
+
+// outputTargetType is output format to write to the file, or stdout when no file is given
+type outputTargetType struct {
+	Format   *outputFormatType
+	Filename string
+}
+
+// printStats adapts format printing function stats only
+func printStats(print func(w io.Writer, arr []complexity.FuncStatsType) error) func(io.Writer, []complexity.FuncStatsType, []foundDiagnosticsStruct) error {
+	return func(w io.Writer, arr []complexity.FuncStatsType, _ []foundDiagnosticsStruct) error {
+		return print(w, arr)
+	}
+}
+
+var outputFormats = []outputFormatType{
+	{Name: "txt", Print: doPrintTxt},
+	{Name: "csv", Print: printStats(func(w io.Writer, arr []complexity.FuncStatsType) error {
+		return doPrintCSV(w, arr, csvColumns, csvAll)
+	})},
+	{Name: "checkstyle", Print: printStats(doPrintcheckstyles)},
+	{Name: "json", Print: printStats(doPrintJSON)},
+	{Name: "sarif", Print: printStats(doPrintSarif)},
+	{Name: "junit", Print: printStats(doPrintJunit)},
+	{Name: "github-actions", Print: printStats(func(w io.Writer, arr []complexity.FuncStatsType) error {
+		if err := doPrintGithubActions(w, arr); err != nil {
+			return err
+		}
+		if githubSummary {
+			return appendGithubSummary(arr)
+		}
+		return nil
+	})},
+	{Name: "gitlab", Print: printStats(doPrintGitlab)},
+	{Name: "html", Print: printStats(doPrintHTML)},
+	{Name: "heatmap", LineStats: true, Print: printStats(doPrintHeatmap)},
+	{Name: "markdown", Print: printStats(func(w io.Writer, arr []complexity.FuncStatsType) error {
+		return doPrintMarkdown(w, arr, markdownTopN, markdownLinkBase, baselineReport)
+	})},
+	{Name: "openmetrics", Print: printStats(doPrintOpenmetrics)},
+	{Name: "sonar", Print: printStats(doPrintSonar)},
+	{Name: "rdjson", Print: printStats(doPrintRdjson)},
+	{Name: "rdjsonl", Print: printStats(doPrintRdjsonl)},
+	{Name: "golangci-json", Print: printStats(doPrintGolangciJSON)},
+	{Name: "template", Print: printStats(func(w io.Writer, arr []complexity.FuncStatsType) error {
+		return doPrintOutTemplate(w, outTemplate, arr)
+	})},
+}
+
+func findOutputFormat(name string) *outputFormatType {
+	for i := range outputFormats {
+		if outputFormats[i].Name == name {
+			return &outputFormats[i]
+		}
+	}
+	return nil
+}
+
+// parseOutputTargets parses comma separated list of format[:file] e.g. "txt,json:reports/complexity.json"
+func parseOutputTargets(s string) ([]outputTargetType, error) {
+	targets := []outputTargetType{}
+	toStdout := ""
+	for _, item := range strings.Split(s, ",") {
+		name, filename, _ := strings.Cut(strings.TrimSpace(item), ":")
+		f := findOutputFormat(name)
+		if f == nil {
+			return nil, fmt.Errorf("unsupported output format %q", name)
+		}
+		if filename == "" {
+			if toStdout != "" {
+				return nil, fmt.Errorf("output formats %q and %q cannot both be written to stdout", toStdout, name)
 			}
+			toStdout = name
+		}
+		targets = append(targets, outputTargetType{Format: f, Filename: filename})
+	}
+	return targets, nil
+}
+
+func configureOutputFormat() error {
+	targets, err := parseOutputTargets(outputFormat)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if t.Format.Name == "template" && outTemplate == nil {
+			return fmt.Errorf("output format template requires -out-template or -out-template-string")
 		}
-	case "csv":
-		complexity.FuncStatsCallback = func(stats complexity.FuncStatsType) {
-			funcStats = append(funcStats, stats)
+		if t.Format.LineStats {
+			complexity.CollectLineStats = true
 		}
 	}
+	outputTargets = targets
+	return nil
 }
 
 func printDiagnostics(arr []foundDiagnosticsStruct) {
-	switch outputFormat {
-	case "checkstyle":
-		doPrintcheckstyles(checkstyles)
-	case "csv":
-		doPrintFuncStats(funcStats)
-	default:
-		doPrintDiagnostics(arr)
+	funcStats := funcStatsOf(arr)
+	for _, t := range outputTargets {
+		err := writeOutput(t.Filename, func(w io.Writer) error {
+			return t.Format.Print(w, funcStats, arr)
+		})
+		if err != nil {
+			log.Print(err)
+		}
 	}
 }
 
-func doPrintFuncStats(arr []complexity.FuncStatsType) {
-	for _, stats := range arr {
-		if stats.IsNotMaintenable || stats.IsTooComplex {
-			fmt.Printf("%s,%d,%s,%d,%d,%0.3f,%0.3f,%0.3f,%d,%d,%t,%t\n",
-				getRelativeFileName(stats.Filename, currDir), stats.Line, stats.FunctionName,
-				stats.CyclomaticComplexity, stats.MaintenabilityIndex, stats.HalsbreadDifficulty,
-				stats.HalsbreadVolume, stats.TimeToCode,
-				stats.LOC, stats.ConstantsLOC,
-				stats.IsTooComplex, stats.IsNotMaintenable)
-		}
+// writeOutput writes to the named file, or to stdout when no name is given
+func writeOutput(filename string, print func(w io.Writer) error) error {
+	if filename == "" {
+		return print(os.Stdout)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
 	}
+	if err = print(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func isFlagGiven(name string) bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) { given = given || f.Name == name })
+	return given
 }
 
 func getRelativeFileName(filename string, basePath string) string {
-	if strings.HasPrefix(filename, basePath+"/") {
+	if basePath != "" && strings.HasPrefix(filename, basePath+"/") {
 		return filename[len(basePath)+1:]
 	}
 	return filename
@@ -1,24 +1,898 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v3"
 
 	"github.com/fikin/go-complexity-analysis"
 )
 
+// setForTest sets the global variable until the test and its subtests complete
+func setForTest[T any](t *testing.T, v *T, value T) {
+	old := *v
+	t.Cleanup(func() { *v = old })
+	*v = value
+}
+
 func TestIt(t *testing.T) {
-	theConfig = &ConfigFile{}
+	setForTest(t, &theConfig, &ConfigFile{})
 	// outputFormat = "stylecheck"
 	// assert.NoError(t, configureConfigIfGiven())
 	// configureOutputFormat()
-	funcsCnt := 0
-	oldFnc := complexity.FuncStatsCallback
-	complexity.FuncStatsCallback = func(s complexity.FuncStatsType) {
-		funcsCnt++
-		oldFnc(s)
+	assert.Equal(t, 1, run([]string{"./../../testdata/src/..."}, complexity.Analyzer))
+	found, err := loadAndAnalyze([]string{"./../../testdata/src/..."}, complexity.Analyzer)
+	assert.NoError(t, err)
+	assert.Equal(t, 19, len(funcStatsOf(found)))
+}
+
+func TestParallelIsDeterministic(t *testing.T) {
+	setForTest(t, &theConfig, &ConfigFile{})
+	setForTest(t, &parallelism, 1)
+	sequential, err := loadAndAnalyze([]string{"./../../testdata/src/..."}, complexity.Analyzer)
+	assert.NoError(t, err)
+	parallelism = 8
+	parallel, err := loadAndAnalyze([]string{"./../../testdata/src/..."}, complexity.Analyzer)
+	assert.NoError(t, err)
+
+	assert.Equal(t, funcStatsOf(sequential), funcStatsOf(parallel))
+	assert.Equal(t, []string{"github.com/fikin/go-complexity-analysis/testdata/src/a", "github.com/fikin/go-complexity-analysis/testdata/src/halstead"},
+		[]string{parallel[0].pkg.PkgPath, parallel[1].pkg.PkgPath})
+}
+
+// funcCountFact is the number of functions of a package
+type funcCountFact struct{ Count int }
+
+func (*funcCountFact) AFact() {}
+
+// funcCountAnalyzer reports the number of functions of the package together with its dependencies
+var funcCountAnalyzer = &analysis.Analyzer{
+	Name:      "funccount",
+	Doc:       "counts functions of packages and their dependencies",
+	Requires:  []*analysis.Analyzer{complexity.Analyzer},
+	FactTypes: []analysis.Fact{new(funcCountFact)},
+	Run: func(pass *analysis.Pass) (interface{}, error) {
+		count := len(pass.ResultOf[complexity.Analyzer].([]complexity.FuncStatsType))
+		pass.ExportPackageFact(&funcCountFact{count})
+		for _, f := range pass.AllPackageFacts() {
+			if f.Package != pass.Pkg {
+				count += f.Fact.(*funcCountFact).Count
+			}
+		}
+		if len(pass.Files) > 0 {
+			pass.Reportf(pass.Files[0].Package, "%d", count)
+		}
+		return nil, nil
+	},
+}
+
+func TestAnalyzerWithFacts(t *testing.T) {
+	setForTest(t, &theConfig, &ConfigFile{})
+	found, err := loadAndAnalyze([]string{"./../../testdata/src/a"}, funcCountAnalyzer)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(found))
+	assert.NoError(t, found[0].err)
+	assert.Equal(t, 1, len(found[0].diagnostics))
+	count, err := strconv.Atoi(found[0].diagnostics[0].Message)
+	assert.NoError(t, err)
+	assert.Greater(t, count, 100, "functions of fmt and its dependencies are counted via facts")
+}
+
+func TestCache(t *testing.T) {
+	setForTest(t, &theConfig, &ConfigFile{})
+	setForTest(t, &cacheDir, t.TempDir())
+
+	uncached, err := loadAndAnalyze([]string{"./../../testdata/src/..."}, complexity.Analyzer)
+	assert.NoError(t, err)
+	entries, err := filepath.Glob(filepath.Join(cacheDir, "*", "*.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	cached, err := loadAndAnalyze([]string{"./../../testdata/src/..."}, complexity.Analyzer)
+	assert.NoError(t, err)
+	assert.Equal(t, funcStatsOf(uncached), funcStatsOf(cached))
+	assert.Equal(t, toTxtLines(uncached), toTxtLines(cached))
+	assert.Equal(t, uncached[0].pkg.PkgPath, cached[0].pkg.PkgPath)
+
+	// cached entries are used as they are
+	for _, fn := range entries {
+		assert.NoError(t, os.WriteFile(fn, []byte(`{"stats":[{"FunctionName":"cached"}],"diagnostics":[]}`), 0o644))
+	}
+	cached, err = loadAndAnalyze([]string{"./../../testdata/src/..."}, complexity.Analyzer)
+	assert.NoError(t, err)
+	assert.Equal(t, []complexity.FuncStatsType{{FunctionName: "cached"}, {FunctionName: "cached"}}, funcStatsOf(cached))
+
+	// unless configuration changes
+	oldCycloOver := complexity.CycloOver
+	defer func() { complexity.CycloOver = oldCycloOver }()
+	complexity.CycloOver++
+	cached, err = loadAndAnalyze([]string{"./../../testdata/src/..."}, complexity.Analyzer)
+	assert.NoError(t, err)
+	assert.Equal(t, len(funcStatsOf(uncached)), len(funcStatsOf(cached)))
+
+	assert.False(t, isCacheable(funcCountAnalyzer))
+	assert.NoError(t, cleanCache(cacheDir))
+	_, err = os.Stat(cacheDir)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NotEmpty(t, cacheBuildID())
+	assert.Equal(t, cacheBuildID(), cacheBuildID())
+
+	// cache clean with --no-cache
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	setForTest(t, &cacheDir, "")
+	assert.NoError(t, os.MkdirAll(defaultCacheDir(), 0o755))
+	assert.Equal(t, 0, runCache([]string{"clean"}, complexity.Analyzer))
+	_, err = os.Stat(defaultCacheDir())
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSyntaxOnly(t *testing.T) {
+	setForTest(t, &theConfig, &ConfigFile{})
+	loaded, err := loadAndAnalyze([]string{"./../../testdata/src/..."}, complexity.Analyzer)
+	assert.NoError(t, err)
+	setForTest(t, &syntaxOnly, true)
+	parsed, err := loadAndAnalyze([]string{"./../../testdata/src/..."}, complexity.Analyzer)
+	assert.NoError(t, err)
+	assert.Equal(t, funcStatsOf(loaded), funcStatsOf(parsed))
+	assert.Equal(t, toTxtLines(loaded), toTxtLines(parsed))
+
+	// half-written code with unavailable dependencies
+	src := "package x\n\nimport \"example.com/missing\"\n\nfunc F(a, b bool) int {\n\tif a && b {\n\t\treturn missing.X(\n\t}\n\treturn 1\n}\n"
+	pkgs, err := parseSyntaxPackages([]string{stdinArg}, strings.NewReader(src))
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(pkgs)) {
+		assert.Equal(t, "x", pkgs[0].Name)
+		assert.NotEmpty(t, pkgs[0].Errors)
+	}
+	found, err := analyze(pkgs, []*analysis.Analyzer{complexity.Analyzer})
+	assert.NoError(t, err)
+	assert.Empty(t, funcStatsOf(found), "the exported analyzer skips packages with errors, as in vet")
+	found, err = analyze(pkgs, []*analysis.Analyzer{runningDespiteErrors(complexity.Analyzer)})
+	assert.NoError(t, err)
+	stats := funcStatsOf(found)
+	if assert.Equal(t, 1, len(stats)) {
+		assert.Equal(t, stdinFileName, stats[0].Filename)
+		assert.Equal(t, "F", stats[0].FunctionName)
+		assert.Equal(t, 3, stats[0].CyclomaticComplexity)
+	}
+}
+
+func TestWatchSession(t *testing.T) {
+	setForTest(t, &theConfig, &ConfigFile{})
+	dir := t.TempDir() // touched files are copies, not the tracked fixtures
+	assert.NoError(t, os.CopyFS(dir, os.DirFS("../../testdata/src")))
+	assert.NoError(t, os.WriteFile(dir+"/go.mod", []byte("module example.com/w\n\ngo 1.24\n"), 0o644))
+	t.Chdir(dir)
+	s := newWatchSession([]string{"./..."}, complexity.Analyzer)
+	n, err := s.refresh()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 19, len(funcStatsOf(s.results())))
+	assert.False(t, s.isChanged())
+
+	n, err = s.refresh()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n, "unchanged packages are not analyzed again")
+
+	fn := "a/a.go"
+	fi, err := os.Stat(fn)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chtimes(fn, fi.ModTime().Add(time.Hour), fi.ModTime().Add(time.Hour)))
+	assert.True(t, s.isChanged())
+	n, err = s.refresh()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 19, len(funcStatsOf(s.results())))
+
+	// packages failing to list are not retried until files change
+	src, err := os.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(fn, []byte("packge a\n"), 0o644))
+	assert.True(t, s.isChanged())
+	_, err = s.refresh()
+	assert.Error(t, err)
+	assert.False(t, s.isChanged())
+	assert.NoError(t, os.WriteFile(fn, src, 0o644))
+	assert.True(t, s.isChanged())
+	n, err = s.refresh()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestWatchOutput(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	baseline := toJSONReport([]complexity.FuncStatsType{
+		{Filename: "/src/a/a.go", Line: 3, PackagePath: "example.com/a", FunctionName: "f", CyclomaticComplexity: 12, IsTooComplex: true},
+		{Filename: "/src/a/a.go", Line: 9, PackagePath: "example.com/a", FunctionName: "g", CyclomaticComplexity: 2},
+	})
+	stats := []complexity.FuncStatsType{
+		{Filename: "/src/a/a.go", Line: 3, PackagePath: "example.com/a", FunctionName: "f", CyclomaticComplexity: 4},
+		{Filename: "/src/a/a.go", Line: 9, PackagePath: "example.com/a", FunctionName: "g", CyclomaticComplexity: 11, IsTooComplex: true},
+	}
+	fset := token.NewFileSet()
+	fa := fset.AddFile("/src/a/a.go", -1, 100)
+	fa.SetLines([]int{0, 10, 20, 30, 40, 50, 60, 70, 80})
+	diags := []foundDiagnosticsStruct{
+		{pkg: &packages.Package{PkgPath: "example.com/a", Fset: fset}, diagnostics: []analysis.Diagnostic{{Pos: fa.Pos(80), Message: complexity.ToCycloDiagnosticMsg(stats[1])}}},
+	}
+
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintWatch(&out, &baseline, stats, diags, false))
+	assert.Equal(t, `+ a/a.go:9:1: func g seems to be complex (cyclomatic complexity=11)
+- a/a.go:3: fixed example.com/a.f (cyclomatic complexity was 12)
+
+2 functions analyzed, 1 thresholds crossed: 1 cyclomatic complexity, 0 maintainability index. 1 new, 1 fixed since watching started.
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, doPrintWatch(&out, &baseline, stats, diags, true))
+	assert.Contains(t, out.String(), ansiRed+"+ a/a.go:9:1:")
+	assert.Contains(t, out.String(), ansiGreen+"- a/a.go:3:")
+}
+
+// lspClientType is a scripted client of the served LSP connection
+type lspClientType struct {
+	t *testing.T
+	w io.Writer
+	r *bufio.Reader
+}
+
+func (c *lspClientType) send(msg map[string]interface{}) {
+	msg["jsonrpc"] = "2.0"
+	assert.NoError(c.t, writeLSPMessage(c.w, msg))
+}
+
+// recv returns the next message with the method, or response if no method is given
+func (c *lspClientType) recv(method string) map[string]interface{} {
+	for {
+		buf, err := readLSPMessage(c.r)
+		if !assert.NoError(c.t, err) {
+			return nil
+		}
+		msg := map[string]interface{}{}
+		assert.NoError(c.t, json.Unmarshal(buf, &msg))
+		if m, _ := msg["method"].(string); m == method {
+			return msg
+		}
+	}
+}
+
+func TestLSP(t *testing.T) {
+	setForTest(t, &theConfig, &ConfigFile{})
+	setForTest(t, &complexity.CollectLineStats, true)
+	fn, err := filepath.Abs("../../testdata/src/a/a.go")
+	assert.NoError(t, err)
+	buf, err := os.ReadFile(fn)
+	assert.NoError(t, err)
+	uri := toFileURI(fn)
+	// unsaved buffer with a function not on disk
+	text := string(buf) + "\nfunc overlay(a, b, c, d, e, f bool) bool {\n\treturn a && b && c && d && e && f || a && b && c && d && e && f\n}\n"
+	overlayLine := strings.Count(string(buf), "\n") + 1 // 0-based
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	exitcode := make(chan int, 1)
+	go func() {
+		exitcode <- serveLSP(inR, outW, complexity.Analyzer)
+		outW.Close()
+	}()
+	c := &lspClientType{t: t, w: inW, r: bufio.NewReader(outR)}
+
+	c.send(map[string]interface{}{"id": 1, "method": "initialize", "params": map[string]interface{}{}})
+	res := c.recv("")["result"].(map[string]interface{})
+	assert.Equal(t, true, res["capabilities"].(map[string]interface{})["hoverProvider"])
+	c.send(map[string]interface{}{"method": "initialized", "params": map[string]interface{}{}})
+
+	c.send(map[string]interface{}{"method": "textDocument/didOpen", "params": map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "go", "version": 1, "text": text},
+	}})
+	params := c.recv("textDocument/publishDiagnostics")["params"].(map[string]interface{})
+	assert.Equal(t, uri, params["uri"])
+	diags := params["diagnostics"].([]interface{})
+	if assert.Equal(t, 1, len(diags)) {
+		d := diags[0].(map[string]interface{})
+		assert.Equal(t, "func overlay seems to be complex (cyclomatic complexity=12)", d["message"])
+		assert.Equal(t, "CyclomaticComplexity", d["code"])
+		assert.Equal(t, float64(1), d["severity"])
+		assert.Equal(t, float64(overlayLine), d["range"].(map[string]interface{})["start"].(map[string]interface{})["line"])
+	}
+
+	c.send(map[string]interface{}{"id": 2, "method": "textDocument/codeLens", "params": map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+	}})
+	lenses := c.recv("")["result"].([]interface{})
+	if assert.Equal(t, 7, len(lenses)) {
+		assert.Equal(t, "cyclo 12 · MI 73 · cognitive 3", lenses[6].(map[string]interface{})["command"].(map[string]interface{})["title"])
+	}
+
+	c.send(map[string]interface{}{"id": 3, "method": "textDocument/hover", "params": map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     map[string]interface{}{"line": overlayLine + 1, "character": 1},
+	}})
+	hover := c.recv("")["result"].(map[string]interface{})["contents"].(map[string]interface{})["value"].(string)
+	assert.Contains(t, hover, "Maintainability index: **73**")
+	assert.Contains(t, hover, "| − 0.23 · cyclomatic complexity 12 | −2.76 |")
+	assert.Contains(t, hover, "Cognitive complexity: **3**")
+	assert.Contains(t, hover, "cyclomatic +11, cognitive +3")
+
+	c.send(map[string]interface{}{"id": 4, "method": "textDocument/definition", "params": map[string]interface{}{}})
+	assert.Equal(t, float64(lspMethodNotFound), c.recv("")["error"].(map[string]interface{})["code"])
+
+	c.send(map[string]interface{}{"method": "textDocument/didClose", "params": map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+	}})
+	assert.Equal(t, 0, len(c.recv("textDocument/publishDiagnostics")["params"].(map[string]interface{})["diagnostics"].([]interface{})))
+
+	c.send(map[string]interface{}{"id": 5, "method": "shutdown"})
+	assert.Nil(t, c.recv("")["result"])
+	c.send(map[string]interface{}{"method": "exit"})
+	assert.Equal(t, 0, <-exitcode)
+}
+
+func TestLSPStaleStats(t *testing.T) {
+	setForTest(t, &theConfig, &ConfigFile{})
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(dir+"/go.mod", []byte("module example.com/l\n\ngo 1.24\n"), 0o644))
+	fn := filepath.Join(dir, "l.go")
+	text := "package l\n\nfunc f(a, b, c, d, e, f bool) bool {\n\treturn a && b && c && d && e && f || a && b && c && d && e && f\n}\n"
+	assert.NoError(t, os.WriteFile(fn, []byte(text), 0o644))
+	uri := toFileURI(fn)
+	open := func(text string) lspRequestType {
+		params, err := json.Marshal(map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri, "text": text}})
+		assert.NoError(t, err)
+		return lspRequestType{Method: "textDocument/didOpen", Params: params}
+	}
+
+	out := bytes.Buffer{}
+	s := newLSPServer(&out, complexity.Analyzer)
+	assert.NoError(t, s.handle(open(text)))
+	assert.NoError(t, s.analyzePending())
+	assert.Len(t, s.stats[fn], 1)
+	assert.Len(t, s.diagnostics(fn), 1)
+
+	// packages failing to load, here for the directory gone, do not keep functions of the last load
+	assert.NoError(t, os.RemoveAll(dir))
+	assert.NoError(t, s.handle(open(text)))
+	assert.NoError(t, s.analyzePending())
+	assert.Empty(t, s.stats[fn])
+	assert.Empty(t, s.diagnostics(fn))
+
+	s.stats[fn] = []complexity.FuncStatsType{{Filename: fn, Line: 3, FunctionName: "f", CyclomaticComplexity: 12, IsTooComplex: true}}
+	out.Reset()
+	assert.NoError(t, s.handle(lspRequestType{Method: "textDocument/didClose", Params: json.RawMessage(`{"textDocument":{"uri":"` + uri + `"}}`)}))
+	assert.NotContains(t, s.stats, fn)
+	assert.Contains(t, out.String(), `"diagnostics":[]`)
+}
+
+func TestLSPPosition(t *testing.T) {
+	content := []byte("package a\r\n\n// é𝄞 x\n")
+	assert.Equal(t, lspPositionTag{Line: 0, Character: 9}, lspPositionOf(content, 1, 0))
+	assert.Equal(t, lspPositionTag{Line: 2, Character: 7}, lspPositionOf(content, 3, 11))
+	assert.Equal(t, lspPositionTag{Line: 2, Character: 8}, lspPositionOf(content, 3, 0))
+}
+
+func TestTxt(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	fset := token.NewFileSet()
+	fa := fset.AddFile("/src/a/a.go", -1, 100)
+	fa.SetLines([]int{0, 10, 20, 30})
+	fb := fset.AddFile("/src/a/b.go", -1, 100)
+	fb.SetLines([]int{0, 10, 20, 30})
+	pkg := &packages.Package{PkgPath: "example.com/a", Fset: fset}
+	diags := []foundDiagnosticsStruct{
+		{pkg: pkg, diagnostics: []analysis.Diagnostic{{Pos: fb.Pos(21), Message: "second\n"}, {Pos: fa.Pos(32), Message: "first"}}},
+	}
+	stats := []complexity.FuncStatsType{
+		{FunctionName: "f", CyclomaticComplexity: 11, IsTooComplex: true},
+		{FunctionName: "g"},
+	}
+
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintTxt(&out, stats, diags))
+	assert.Equal(t, "a/a.go:4:3: first\na/b.go:3:2: second\n\n2 functions analyzed, 1 thresholds crossed: 1 cyclomatic complexity, 0 maintainability index.\n", out.String())
+}
+
+func TestCSV(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	arr := []complexity.FuncStatsType{
+		{Filename: "/src/a/b.go", Line: 5, PackagePath: "example.com/a", FunctionName: "g", CyclomaticComplexity: 2, MaintenabilityIndex: 80},
+		{Filename: "/src/a/a,b.go", Line: 7, Column: 2, ModulePath: "example.com", PackagePath: "example.com/a", FunctionName: "f", CyclomaticComplexity: 11, CognitiveComplexity: 4, HalsbreadVolume: 1.5, IsTooComplex: true},
+	}
+	cols, err := parseCSVColumns(csvDefaultColumns)
+	assert.NoError(t, err)
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintCSV(&out, arr, cols, false))
+	assert.Equal(t, csvDefaultColumns+"\n\"a/a,b.go\",7,f,11,0,0.000,1.500,0.000,0,0,true,false\n", out.String())
+
+	cols, err = parseCSVColumns("qualifiedName, cyclomaticComplexity")
+	assert.NoError(t, err)
+	out.Reset()
+	assert.NoError(t, doPrintCSV(&out, arr, cols, true))
+	assert.Equal(t, "qualifiedName,cyclomaticComplexity\nexample.com/a.f,11\nexample.com/a.g,2\n", out.String())
+
+	cols, err = parseCSVColumns("file,line,column,module,cognitiveComplexity")
+	assert.NoError(t, err)
+	out.Reset()
+	assert.NoError(t, doPrintCSV(&out, arr, cols, false))
+	assert.Equal(t, "file,line,column,module,cognitiveComplexity\n\"a/a,b.go\",7,2,example.com,4\n", out.String())
+
+	_, err = parseCSVColumns("file,col")
+	assert.Error(t, err)
+}
+
+func TestCheckstyle(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	arr := []complexity.FuncStatsType{
+		{Filename: "/src/b/b.go", Line: 5, Column: 1, FunctionName: "g", MaintenabilityIndex: 15, IsNotMaintenable: true},
+		{Filename: "/src/a/a.go", Line: 9, Column: 2, FunctionName: "f", CyclomaticComplexity: 25, MaintenabilityIndex: 5, IsTooComplex: true, IsNotMaintenable: true},
+	}
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintcheckstyles(&out, arr))
+	assert.True(t, strings.HasPrefix(out.String(), xml.Header))
+	data := checkstyleTag{}
+	assert.NoError(t, xml.Unmarshal(out.Bytes(), &data))
+	assert.Equal(t, []string{"a/a.go", "b/b.go"}, []string{data.Files[0].FileName, data.Files[1].FileName})
+	assert.Len(t, data.Files[0].Errors, 2)
+	assert.Equal(t, checkstyleErrorTag{XMLName: xml.Name{Local: "error"}, Col: 2, Line: 9, Msg: data.Files[0].Errors[0].Msg, Severity: "error", Source: "complexity.cyclomatic"}, data.Files[0].Errors[0])
+	assert.Equal(t, "error", data.Files[0].Errors[1].Severity)
+	assert.Equal(t, "complexity.maintainability", data.Files[0].Errors[1].Source)
+	assert.Equal(t, "warning", data.Files[1].Errors[0].Severity)
+
+	oldCyclo, oldMaint := cycloSeverityTiers, maintSeverityTiers
+	defer func() { cycloSeverityTiers, maintSeverityTiers = oldCyclo, oldMaint }()
+	c := &ConfigFile{}
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+linters-settings:
+  complexity:
+    severity:
+      cyclo:
+        - over: 10
+          severity: warning
+        - over: 20
+          severity: error
+      maint:
+        - under: 20
+          severity: info
+`), c))
+	assert.NoError(t, configureSeverityTiers(c))
+	assert.Equal(t, []severityTierType{{Bound: 20, Severity: "error"}, {Bound: 10, Severity: "warning"}}, cycloSeverityTiers)
+	arr[1].CyclomaticComplexity = 15
+	data = toCheckstyles(arr)
+	assert.Equal(t, "warning", data.Files[0].Errors[0].Severity)
+	assert.Equal(t, "info", data.Files[0].Errors[1].Severity)
+
+	c.LintersSettings.Complexity.Severity.Maint[0].Severity = "fatal"
+	assert.Error(t, configureSeverityTiers(c))
+}
+
+func TestJSONMatchesSchema(t *testing.T) {
+	type schemaType struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]schemaType      `json:"$defs"`
+	}
+	buf, err := os.ReadFile("report.schema.json")
+	assert.NoError(t, err)
+	schema := schemaType{}
+	assert.NoError(t, json.Unmarshal(buf, &schema))
+
+	setForTest(t, &currDir, "/src")
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintJSON(&out, []complexity.FuncStatsType{
+		{Filename: "/src/b.go", Line: 3, PackagePath: "example.com/a", FunctionName: "g"},
+		{Filename: "/src/a.go", Line: 7, Column: 2, ModulePath: "example.com", PackagePath: "example.com/a", Receiver: "*T", FunctionName: "f", CyclomaticComplexity: 11, CognitiveComplexity: 4, IsTooComplex: true},
+	}))
+	report := map[string]json.RawMessage{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assertMatchesSchema := func(obj map[string]json.RawMessage, s schemaType) {
+		for _, r := range s.Required {
+			assert.Contains(t, obj, r)
+		}
+		for k := range obj {
+			assert.Contains(t, s.Properties, k)
+		}
+	}
+	assertMatchesSchema(report, schema)
+
+	funcs := []map[string]json.RawMessage{}
+	assert.NoError(t, json.Unmarshal(report["functions"], &funcs))
+	assert.Len(t, funcs, 2)
+	for _, f := range funcs {
+		assertMatchesSchema(f, schema.Defs["function"])
+	}
+	assert.Equal(t, `"a.go"`, string(funcs[0]["file"]))
+	assert.Equal(t, `"example.com/a.(*T).f"`, string(funcs[0]["qualifiedName"]))
+	assert.Equal(t, "2", string(funcs[0]["column"]))
+	assert.Equal(t, `"example.com"`, string(funcs[0]["module"]))
+	assert.Equal(t, "4", string(funcs[0]["cognitiveComplexity"]))
+	assert.NotContains(t, funcs[1], "receiver")
+	assert.NotContains(t, funcs[1], "module")
+}
+
+func TestSarif(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintSarif(&out, []complexity.FuncStatsType{
+		{Filename: "/src/a.go", Line: 7, EndLine: 30, FunctionName: "f", CyclomaticComplexity: 11, MaintenabilityIndex: 5, IsTooComplex: true, IsNotMaintenable: true},
+		{Filename: "/zz/b.go", Line: 3, EndLine: 5, FunctionName: "g", MaintenabilityIndex: 15, IsNotMaintenable: true},
+		{Filename: "/src/c.go", Line: 1, EndLine: 2, FunctionName: "h"},
+	}))
+	log := sarifLogTag{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, len(metrics))
+	res := log.Runs[0].Results
+	assert.Len(t, res, 3)
+	assert.Equal(t, []string{"cyclomatic", "maintainability", "maintainability"}, []string{res[0].RuleID, res[1].RuleID, res[2].RuleID})
+	assert.Equal(t, []string{"error", "error", "warning"}, []string{res[0].Level, res[1].Level, res[2].Level})
+	loc := res[0].Locations[0].PhysicalLocation
+	assert.Equal(t, sarifArtifactLocationTag{URI: "a.go", URIBaseID: "%SRCROOT%"}, loc.ArtifactLocation)
+	assert.Equal(t, sarifRegionTag{StartLine: 7, EndLine: 30}, loc.Region)
+	assert.Equal(t, "file:///zz/b.go", res[2].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 11, res[0].Properties.CyclomaticComplexity)
+}
+
+func TestJunit(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintJunit(&out, []complexity.FuncStatsType{
+		{Filename: "/src/b/b.go", Line: 3, PackagePath: "example.com/b", FunctionName: "g"},
+		{Filename: "/src/a/a.go", Line: 7, PackagePath: "example.com/a", Receiver: "*T", FunctionName: "f", CyclomaticComplexity: 11, MaintenabilityIndex: 5, IsTooComplex: true, IsNotMaintenable: true},
+		{Filename: "/src/a/a.go", Line: 20, PackagePath: "example.com/a", FunctionName: "h"},
+	}))
+	assert.True(t, bytes.HasPrefix(out.Bytes(), []byte("<?xml")))
+	data := junitTestsuitesTag{}
+	assert.NoError(t, xml.Unmarshal(out.Bytes(), &data))
+	assert.Equal(t, 3, data.Tests)
+	assert.Equal(t, 1, data.Failures)
+	assert.Len(t, data.Testsuites, 2)
+	a := data.Testsuites[0]
+	assert.Equal(t, "example.com/a", a.Name)
+	assert.Equal(t, 2, a.Tests)
+	assert.Equal(t, 1, a.Failures)
+	assert.Equal(t, "(*T).f", a.Testcases[0].Name)
+	assert.Equal(t, "a/a.go", a.Testcases[0].File)
+	assert.Equal(t, "cyclomatic,maintainability", a.Testcases[0].Failure.Type)
+	assert.Contains(t, a.Testcases[0].Failure.Text, "cyclomatic complexity: 11")
+	assert.Nil(t, a.Testcases[1].Failure)
+}
+
+func TestGithubActions(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	arr := []complexity.FuncStatsType{
+		{Filename: "/src/a,b.go", Line: 7, EndLine: 30, FunctionName: "f", CyclomaticComplexity: 11, MaintenabilityIndex: 15, IsTooComplex: true, IsNotMaintenable: true},
+		{Filename: "/src/c.go", Line: 1, EndLine: 2, FunctionName: "h"},
+	}
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintGithubActions(&out, arr))
+	assert.Equal(t, "::error file=a%2Cb.go,line=7,endLine=30,title=Cyclomatic complexity::func f seems to be complex (cyclomatic complexity=11)\n"+
+		"::warning file=a%2Cb.go,line=7,endLine=30,title=Maintainability index::func f seems to have low maintainability (maintainability index=15)\n",
+		out.String())
+
+	t.Setenv(githubSummaryEnv, t.TempDir()+"/summary.md")
+	assert.NoError(t, appendGithubSummary(arr))
+	assert.NoError(t, appendGithubSummary(arr))
+	buf, err := os.ReadFile(os.Getenv(githubSummaryEnv))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(buf), "2 functions analyzed, 2 thresholds crossed."))
+	assert.Contains(t, string(buf), "| a,b.go:7 | `f` | Cyclomatic complexity | 11 | 10 |")
+}
+
+func TestGitlab(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	f := complexity.FuncStatsType{Filename: "/src/a/a.go", Line: 7, EndLine: 30, PackagePath: "example.com/a", FunctionName: "f", CyclomaticComplexity: 11, MaintenabilityIndex: 15, IsTooComplex: true, IsNotMaintenable: true}
+	moved := f
+	moved.Filename, moved.Line, moved.EndLine = "/src/a/b.go", 70, 93
+
+	issues := toGitlabIssues([]complexity.FuncStatsType{f})
+	assert.Len(t, issues, 2)
+	assert.Equal(t, "complexity/cyclomatic", issues[0].CheckName)
+	assert.Equal(t, "major", issues[0].Severity)
+	assert.Equal(t, "minor", issues[1].Severity)
+	assert.Equal(t, gitlabLocationTag{Path: "a/a.go", Lines: gitlabLinesTag{Begin: 7, End: 30}}, issues[0].Location)
+	assert.NotEqual(t, issues[0].Fingerprint, issues[1].Fingerprint)
+	assert.Equal(t, issues[0].Fingerprint, toGitlabIssues([]complexity.FuncStatsType{moved})[0].Fingerprint)
+
+	// functions sharing the name, e.g. init ones, get their own issues
+	issues = toGitlabIssues([]complexity.FuncStatsType{f, moved})
+	assert.Len(t, issues, 4)
+	assert.Equal(t, issues[0].Fingerprint, toGitlabIssues([]complexity.FuncStatsType{f})[0].Fingerprint)
+	assert.NotEqual(t, issues[0].Fingerprint, issues[2].Fingerprint)
+
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintGitlab(&out, nil))
+	assert.Equal(t, "[]\n", out.String())
+}
+
+func TestMarkdown(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	setForTest(t, &complexity.CycloOver, 10)
+	setForTest(t, &complexity.MaintUnder, 20)
+	ok := complexity.FuncStatsType{Filename: "/src/a/a.go", Line: 3, PackagePath: "example.com/a", FunctionName: "ok", CyclomaticComplexity: 2, MaintenabilityIndex: 80}
+	complex := complexity.FuncStatsType{Filename: "/src/a/a.go", Line: 10, PackagePath: "example.com/a", FunctionName: "complex", CyclomaticComplexity: 12, MaintenabilityIndex: 40, IsTooComplex: true}
+	both := complexity.FuncStatsType{Filename: "/src/a/b.go", Line: 5, PackagePath: "example.com/a", FunctionName: "both", CyclomaticComplexity: 11, MaintenabilityIndex: 15, IsTooComplex: true, IsNotMaintenable: true}
+	arr := []complexity.FuncStatsType{ok, complex, both}
+
+	worst := worstFuncStats(arr, 2)
+	assert.Equal(t, []string{"both", "complex"}, []string{worst[0].FunctionName, worst[1].FunctionName})
+	assert.Empty(t, worstFuncStats(arr, -1))
+	assert.Equal(t, "[a/b.go:5](https://x/a/b.go#L5)", toMarkdownLink("a/b.go", 5, "https://x/"))
+
+	moved := complex
+	moved.Line = 40
+	baseline := toJSONReport([]complexity.FuncStatsType{moved, ok})
+	baseline.Functions[0].IsNotMaintainable = true // ok, sorted before moved complex
+	newViolations, fixedViolations := compareBaseline(&baseline, arr)
+	assert.Len(t, newViolations, 2)
+	assert.Equal(t, "example.com/a.both", newViolations[0].QualifiedName)
+	assert.Len(t, fixedViolations, 1)
+	assert.Equal(t, "example.com/a.ok", fixedViolations[0].QualifiedName)
+
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintMarkdown(&out, arr, 10, "", &baseline))
+	assert.Contains(t, out.String(), "**3** functions in **1** packages analyzed: **2** too complex")
+	assert.Contains(t, out.String(), "### New violations: 2")
+	assert.Contains(t, out.String(), "### Fixed violations: 1")
+}
+
+func TestOpenmetrics(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	arr := []complexity.FuncStatsType{
+		{Filename: "/src/a/a.go", Line: 3, PackagePath: "example.com/a", FunctionName: "init", CyclomaticComplexity: 2, MaintenabilityIndex: 80},
+		{Filename: "/src/a/a.go", Line: 9, PackagePath: "example.com/a", FunctionName: "init", CyclomaticComplexity: 4, MaintenabilityIndex: 60},
+		{Filename: "/src/a/b.go", Line: 5, PackagePath: "example.com/a", Receiver: "*T", FunctionName: "M", CyclomaticComplexity: 12, MaintenabilityIndex: 15, IsTooComplex: true, IsNotMaintenable: true},
+	}
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintOpenmetrics(&out, arr))
+	s := out.String()
+	assert.Contains(t, s, "# TYPE go_complexity_cyclomatic gauge\n")
+	assert.Contains(t, s, `go_complexity_cyclomatic{package="example.com/a",function="init",file="a/a.go"} 2`+"\n")
+	assert.Contains(t, s, `go_complexity_cyclomatic{package="example.com/a",function="init#2",file="a/a.go"} 4`+"\n")
+	assert.Contains(t, s, `go_complexity_maintainability_index{package="example.com/a",function="(*T).M",file="a/b.go"} 15`+"\n")
+	assert.Contains(t, s, `go_complexity_package_cyclomatic_avg{package="example.com/a"} 6`+"\n")
+	assert.Contains(t, s, `go_complexity_package_too_complex_functions{package="example.com/a"} 1`+"\n")
+	assert.True(t, strings.HasSuffix(s, "# EOF\n"))
+	assert.Equal(t, `a\\b\"c\nd`, escapeOpenmetricsLabel("a\\b\"c\nd"))
+}
+
+func TestSonar(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	f := complexity.FuncStatsType{Filename: "/src/a/a.go", Line: 7, EndLine: 30, PackagePath: "example.com/a", FunctionName: "f", CyclomaticComplexity: 11, MaintenabilityIndex: 15, TimeToCode: 0.25, IsTooComplex: true, IsNotMaintenable: true}
+
+	report := toSonarReport([]complexity.FuncStatsType{f})
+	assert.Len(t, report.Issues, 2)
+	assert.Equal(t, "complexity", report.Issues[0].EngineID)
+	assert.Equal(t, "cyclomatic", report.Issues[0].RuleID)
+	assert.Equal(t, "MAJOR", report.Issues[0].Severity)
+	assert.Equal(t, "CODE_SMELL", report.Issues[0].Type)
+	assert.Equal(t, 15, report.Issues[0].EffortMinutes)
+	assert.Equal(t, sonarLocationTag{Message: report.Issues[0].PrimaryLocation.Message, FilePath: "a/a.go", TextRange: sonarTextRangeTag{StartLine: 7, EndLine: 30}}, report.Issues[0].PrimaryLocation)
+	assert.Equal(t, "maintainability", report.Issues[1].RuleID)
+	assert.Equal(t, "MINOR", report.Issues[1].Severity)
+
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintSonar(&out, nil))
+	assert.JSONEq(t, `{"issues":[]}`, out.String())
+}
+
+func TestRdjson(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	f := complexity.FuncStatsType{Filename: "/src/a/a.go", Line: 7, EndLine: 30, PackagePath: "example.com/a", FunctionName: "f", CyclomaticComplexity: 11, MaintenabilityIndex: 15, IsTooComplex: true, IsNotMaintenable: true}
+
+	diags := toRdjsonDiagnostics([]complexity.FuncStatsType{f})
+	assert.Len(t, diags, 2)
+	assert.Equal(t, "cyclomatic", diags[0].Code.Value)
+	assert.Equal(t, "complexity", diags[0].Source.Name)
+	assert.Equal(t, "ERROR", diags[0].Severity)
+	assert.Equal(t, "WARNING", diags[1].Severity)
+	assert.Equal(t, rdjsonLocationTag{Path: "a/a.go", Range: rdjsonRangeTag{Start: rdjsonPositionTag{Line: 7}, End: &rdjsonPositionTag{Line: 30}}}, diags[0].Location)
+
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintRdjsonl(&out, []complexity.FuncStatsType{f}))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+	d := rdjsonDiagnosticTag{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &d))
+	assert.Equal(t, "maintainability", d.Code.Value)
+
+	out.Reset()
+	assert.NoError(t, doPrintRdjson(&out, nil))
+	assert.JSONEq(t, `{"source":{"name":"complexity","url":"https://github.com/fikin/go-complexity-analysis"},"severity":"WARNING","diagnostics":[]}`, out.String())
+}
+
+func TestGolangciJSON(t *testing.T) {
+	dir := t.TempDir()
+	setForTest(t, &currDir, dir)
+	assert.NoError(t, os.WriteFile(dir+"/a.go", []byte("package a\r\n\r\n func f() {\r\n}\r\n"), 0o644))
+	f := complexity.FuncStatsType{Filename: dir + "/a.go", Line: 3, Column: 2, Offset: 13, EndLine: 4, PackagePath: "example.com/a", FunctionName: "f", CyclomaticComplexity: 11, MaintenabilityIndex: 50, IsTooComplex: true}
+
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintGolangciJSON(&out, []complexity.FuncStatsType{f}))
+	report := golangciReportTag{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Len(t, report.Issues, 1)
+	assert.Equal(t, "complexity", report.Issues[0].FromLinter)
+	assert.Equal(t, "error", report.Issues[0].Severity)
+	assert.Equal(t, []string{" func f() {"}, report.Issues[0].SourceLines)
+	assert.Equal(t, golangciPositionTag{Filename: "a.go", Offset: 13, Line: 3, Column: 2}, report.Issues[0].Pos)
+	assert.Equal(t, []golangciLinterTag{{Name: "complexity", Enabled: true}}, report.Report.Linters)
+}
+
+func TestOutTemplate(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	arr := []complexity.FuncStatsType{
+		{Filename: "/src/a/b.go", Line: 5, PackagePath: "example.com/a", FunctionName: "g", CyclomaticComplexity: 2, MaintenabilityIndex: 15, IsNotMaintenable: true},
+		{Filename: "/src/a/a.go", Line: 7, PackagePath: "example.com/a", FunctionName: "f", CyclomaticComplexity: 11, MaintenabilityIndex: 15, IsTooComplex: true, IsNotMaintenable: true},
 	}
+	tmpl, err := loadOutTemplate("", `{{range .Functions}}{{relpath .Filename}}:{{.Line}} {{severity .}} {{len (violations .)}}
+{{end}}{{range .Violations}}{{.Metric}} {{.Func.FunctionName}}
+{{end}}{{range .Packages}}{{printf "%s %.1f" .Package .AvgCyclo}}{{end}}`)
+	assert.NoError(t, err)
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintOutTemplate(&out, tmpl, arr))
+	assert.Equal(t, "a/a.go:7 error 2\na/b.go:5 warning 1\ncyclomatic f\nmaintainability f\nmaintainability g\nexample.com/a 6.5", out.String())
+
+	// worst severity wins regardless of the order of violations
+	setForTest(t, &cycloSeverityTiers, []severityTierType{{Bound: 0, Severity: severityInfo}})
+	tmpl, err = loadOutTemplate("", `{{range .Functions}}{{severity .}}{{end}}`)
+	assert.NoError(t, err)
+	out.Reset()
+	assert.NoError(t, doPrintOutTemplate(&out, tmpl, arr[1:]))
+	assert.Equal(t, "warning", out.String())
+
+	_, err = loadOutTemplate("x.tmpl", "{{.}}")
+	assert.Error(t, err)
+	_, err = loadOutTemplate("", "{{.Unclosed")
+	assert.Error(t, err)
+}
+
+func TestHTML(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintHTML(&out, []complexity.FuncStatsType{
+		{Filename: "/src/a/a.go", Line: 7, EndLine: 30, PackagePath: "example.com/a", Receiver: "*T", FunctionName: "f", CyclomaticComplexity: 11, MaintenabilityIndex: 15, IsTooComplex: true, IsNotMaintenable: true},
+		{Filename: "/src/a/a.go", Line: 40, EndLine: 42, PackagePath: "example.com/a", FunctionName: "g", CyclomaticComplexity: 1, MaintenabilityIndex: 80},
+	}))
+	html := out.String()
+	assert.NotContains(t, html, "ZgotmplZ")
+	assert.Contains(t, html, `<tr class="red" data-pkg="example.com/a">`)
+	assert.Contains(t, html, `<tr class="green" data-pkg="example.com/a">`)
+	assert.Contains(t, html, "(*T).f</a>")
+	assert.Contains(t, html, "source not available")
+}
+
+func TestHistogram(t *testing.T) {
+	bars := toHistogram([]int{1, 2, 2, 5, 40}, []int{1, 2, 3, 31}, cycloBand)
+	assert.Equal(t, []htmlBarType{
+		{Label: "1", Count: 1, Height: 50, Band: bandGreen},
+		{Label: "2", Count: 2, Height: 100, Band: bandGreen},
+		{Label: "3-30", Count: 1, Height: 50, Band: bandGreen},
+		{Label: "31+", Count: 1, Height: 50, Band: bandRed},
+	}, bars)
+}
+
+func TestHeatmap(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	data := toHeatmapData([]complexity.FuncStatsType{{
+		Filename: "main_test.go", Line: 1, FunctionName: "f",
+		LineStats: []complexity.LineStatsType{{Line: 1, Cyclomatic: 1, Operators: 4}, {Line: 3, Cyclomatic: 7, Operators: 9, Operands: 8}},
+	}})
+	assert.Len(t, data.Files, 1)
+	lines := data.Files[0].Lines
+	assert.Equal(t, "package main", lines[0].Text)
+	assert.Equal(t, []int{1, 1}, []int{lines[0].CycloLevel, lines[0].HalstLevel})
+	assert.Equal(t, []int{0, 0}, []int{lines[1].CycloLevel, lines[1].HalstLevel})
+	assert.Equal(t, []int{5, 5}, []int{lines[2].CycloLevel, lines[2].HalstLevel})
+	assert.Equal(t, "f: cyclomatic +7, operators 9, operands 8", lines[2].Title)
+
+	// functions sharing a line, the test variant repeating one of them
+	g := complexity.FuncStatsType{Filename: "main_test.go", Line: 1, FunctionName: "g", LineStats: []complexity.LineStatsType{{Line: 1, Cyclomatic: 2}}}
+	data = toHeatmapData([]complexity.FuncStatsType{g, g, {
+		Filename: "main_test.go", Line: 1, FunctionName: "f", LineStats: []complexity.LineStatsType{{Line: 1, Operators: 4}},
+	}})
+	lines = data.Files[0].Lines
+	assert.Equal(t, []int{2, 1}, []int{lines[0].CycloLevel, lines[0].HalstLevel})
+	assert.Equal(t, "g: cyclomatic +2, operators 0, operands 0\nf: cyclomatic +0, operators 4, operands 0", lines[0].Title)
+
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintHeatmap(&out, nil))
+}
+
+func TestSquarify(t *testing.T) {
+	rects := squarify([]float64{6, 6, 4, 3, 2, 2, 1}, treemapRect{W: 6, H: 4})
+	assert.Len(t, rects, 7)
+	area := 0.0
+	for _, r := range rects {
+		area += r.W * r.H
+		assert.True(t, r.X >= 0 && r.Y >= 0 && r.X+r.W <= 6.0001 && r.Y+r.H <= 4.0001, "%v outside", r)
+	}
+	assert.InDelta(t, 24, area, 0.0001)
+}
+
+func TestTreemap(t *testing.T) {
+	setForTest(t, &currDir, "/src")
+	arr := []complexity.FuncStatsType{
+		{Filename: "/src/a/a.go", ModulePath: "example.com", PackagePath: "example.com/a", FunctionName: "f", LOC: 30, MaintenabilityIndex: 5},
+		{Filename: "/src/a/b.go", ModulePath: "example.com", PackagePath: "example.com/a", FunctionName: "g", LOC: 10, MaintenabilityIndex: 50},
+		{Filename: "/src/c/c.go", ModulePath: "example.com", PackagePath: "example.com/c", FunctionName: "h", LOC: 20, CyclomaticComplexity: 11},
+	}
+	root := buildTreemap(arr, treemapColorCyclo)
+	assert.Equal(t, "example.com", root.Label)
+	assert.Equal(t, 60, root.LOC)
+	assert.Equal(t, []string{"a", "c"}, []string{root.Children[0].Label, root.Children[1].Label})
+	assert.Equal(t, "a.go", root.Children[0].Children[0].Label)
+	assert.Equal(t, treemapBandFills[bandRed], root.Children[1].Children[0].Children[0].Fill)
+
+	out := bytes.Buffer{}
+	assert.NoError(t, doPrintTreemap(&out, arr, treemapColorMaint, 600, 400))
+	dec := xml.NewDecoder(&out)
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		if err != nil {
+			break
+		}
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("t", flag.ContinueOnError)
+	o := fs.String("o", "", "")
+	args, err := parseInterspersed(fs, []string{"./...", "-o", "out.svg", "./b"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"./...", "./b"}, args)
+	assert.Equal(t, "out.svg", *o)
+}
+
+func TestOutputTargets(t *testing.T) {
+	targets, err := parseOutputTargets("txt,checkstyle:reports/complexity.xml,json:C:\\reports\\complexity.json")
+	assert.NoError(t, err)
+	assert.Len(t, targets, 3)
+	assert.Equal(t, "txt", targets[0].Format.Name)
+	assert.Equal(t, "", targets[0].Filename)
+	assert.Equal(t, "reports/complexity.xml", targets[1].Filename)
+	assert.Equal(t, "C:\\reports\\complexity.json", targets[2].Filename)
+	_, err = parseOutputTargets("txt,yaml")
+	assert.Error(t, err)
+	_, err = parseOutputTargets("txt,json")
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	setForTest(t, &theConfig, &ConfigFile{})
+	setForTest(t, &outputTargets, nil)
+	setForTest(t, &complexity.CollectLineStats, false)
+	setForTest(t, &outputFormat, "json:"+dir+"/reports/complexity.json,checkstyle:"+dir+"/complexity.xml,heatmap:"+dir+"/heatmap.html")
+	assert.NoError(t, configureOutputFormat())
+	assert.True(t, complexity.CollectLineStats)
 	assert.Equal(t, 1, run([]string{"./../../testdata/src/..."}, complexity.Analyzer))
-	assert.Equal(t, 19, funcsCnt)
+
+	buf, err := os.ReadFile(dir + "/reports/complexity.json")
+	assert.NoError(t, err)
+	report := jsonReportTag{}
+	assert.NoError(t, json.Unmarshal(buf, &report))
+	assert.Len(t, report.Functions, 19)
+	buf, err = os.ReadFile(dir + "/complexity.xml")
+	assert.NoError(t, err)
+	assert.NoError(t, xml.Unmarshal(buf, &checkstyleTag{}))
+	_, err = os.Stat(dir + "/heatmap.html")
+	assert.NoError(t, err)
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// worstFuncStats returns up to n functions crossing most thresholds,
+// then by highest cyclomatic complexity and lowest maintainability index
+func worstFuncStats(arr []complexity.FuncStatsType, n int) []complexity.FuncStatsType {
+	sorted := sortedFuncStats(arr)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		vi, vj := len(violationsOf(sorted[i])), len(violationsOf(sorted[j]))
+		if vi != vj {
+			return vi > vj
+		}
+		if sorted[i].CyclomaticComplexity != sorted[j].CyclomaticComplexity {
+			return sorted[i].CyclomaticComplexity > sorted[j].CyclomaticComplexity
+		}
+		return sorted[i].MaintenabilityIndex < sorted[j].MaintenabilityIndex
+	})
+	if len(sorted) > n {
+		sorted = sorted[:max(n, 0)]
+	}
+	return sorted
+}
+
+// toMarkdownLink links file line as path#Lline, relative to linkBase if given
+func toMarkdownLink(file string, line int, linkBase string) string {
+	file = filepath.ToSlash(file)
+	return fmt.Sprintf("[%s:%d](%s%s#L%d)", file, line, linkBase, file, line)
+}
+
+func doPrintMarkdown(w io.Writer, arr []complexity.FuncStatsType, topN int, linkBase string, baseline *jsonReportTag) error {
+	bw := bufio.NewWriter(w)
+	pkgs := toPackageStats(arr)
+	tooComplex, notMaintainable := 0, 0
+	for _, p := range pkgs {
+		tooComplex += p.TooComplex
+		notMaintainable += p.NotMaintainable
+	}
+	fmt.Fprintf(bw, "## Complexity report\n\n")
+	fmt.Fprintf(bw, "**%d** functions in **%d** packages analyzed: **%d** too complex (cyclomatic complexity > %d), **%d** not maintainable (maintainability index < %d).\n",
+		len(arr), len(pkgs), tooComplex, complexity.CycloOver, notMaintainable, complexity.MaintUnder)
+
+	if baseline != nil {
+		newViolations, fixedViolations := compareBaseline(baseline, arr)
+		fmt.Fprintf(bw, "\n### New violations: %d\n", len(newViolations))
+		printMarkdownViolations(bw, newViolations, linkBase)
+		fmt.Fprintf(bw, "\n### Fixed violations: %d\n", len(fixedViolations))
+		printMarkdownViolations(bw, fixedViolations, linkBase)
+	}
+
+	if worst := worstFuncStats(arr, topN); len(worst) > 0 {
+		fmt.Fprintf(bw, "\n### Top %d functions\n\n", len(worst))
+		fmt.Fprintln(bw, "| Function | Location | Cyclomatic | Maintainability | LOC |")
+		fmt.Fprintln(bw, "|---|---|---:|---:|---:|")
+		for _, stats := range worst {
+			fmt.Fprintf(bw, "| `%s` | %s | %d | %d | %d |\n",
+				stats.LocalName(), toMarkdownLink(getRelativeFileName(stats.Filename, currDir), stats.Line, linkBase),
+				stats.CyclomaticComplexity, stats.MaintenabilityIndex, stats.LOC)
+		}
+	}
+
+	if len(pkgs) > 0 {
+		fmt.Fprintf(bw, "\n### Packages\n\n")
+		fmt.Fprintln(bw, "| Package | Functions | Avg cyclomatic | Max cyclomatic | Avg maintainability | Min maintainability | Too complex | Not maintainable |")
+		fmt.Fprintln(bw, "|---|---:|---:|---:|---:|---:|---:|---:|")
+		for _, p := range pkgs {
+			fmt.Fprintf(bw, "| `%s` | %d | %0.1f | %d | %0.1f | %d | %d | %d |\n",
+				p.Package, p.Functions, p.AvgCyclo(), p.MaxCyclo, p.AvgMaint(), p.MinMaint, p.TooComplex, p.NotMaintainable)
+		}
+	}
+	return bw.Flush()
+}
+
+func printMarkdownViolations(w io.Writer, arr []baselineViolationType, linkBase string) {
+	if len(arr) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n| Function | Location | Metric | Value |")
+	fmt.Fprintln(w, "|---|---|---|---:|")
+	for _, v := range arr {
+		fmt.Fprintf(w, "| `%s` | %s | %s | %d |\n", v.QualifiedName, toMarkdownLink(v.File, v.Line, linkBase), metrics[metricIndex(v.Metric)].Title, v.Value)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// openmetricsPrefix is prefix of all metric family names
+const openmetricsPrefix = "go_complexity_"
+
+// openmetricsFamily is a gauge metric family, each sample being label values and value
+type openmetricsFamily struct {
+	Name    string
+	Help    string
+	Labels  []string
+	Samples []openmetricsSample
+}
+
+type openmetricsSample struct {
+	LabelValues []string
+	Value       float64
+}
+
+func (f *openmetricsFamily) add(value float64, labelValues ...string) {
+	f.Samples = append(f.Samples, openmetricsSample{LabelValues: labelValues, Value: value})
+}
+
+// escapeOpenmetricsLabel escapes label value as of text exposition format
+func escapeOpenmetricsLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatOpenmetricsValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// toOpenmetricsFamilies builds per function gauges, labelled by package, function and file,
+// followed by per package aggregates.
+func toOpenmetricsFamilies(arr []complexity.FuncStatsType) []*openmetricsFamily {
+	fncLabels := []string{"package", "function", "file"}
+	cyclo := &openmetricsFamily{Name: "cyclomatic", Help: "Cyclomatic complexity of the function.", Labels: fncLabels}
+	maint := &openmetricsFamily{Name: "maintainability_index", Help: "Maintainability index of the function.", Labels: fncLabels}
+	difficulty := &openmetricsFamily{Name: "halstead_difficulty", Help: "Halstead difficulty of the function.", Labels: fncLabels}
+	volume := &openmetricsFamily{Name: "halstead_volume", Help: "Halstead volume of the function.", Labels: fncLabels}
+	loc := &openmetricsFamily{Name: "loc", Help: "Lines of code of the function.", Labels: fncLabels}
+
+	// series must be unique, e.g. several init functions in a file are numbered
+	seen := map[string]int{}
+	for _, stats := range sortedFuncStats(arr) {
+		file := getRelativeFileName(stats.Filename, currDir)
+		name := stats.LocalName()
+		key := stats.PackagePath + "\x00" + name + "\x00" + file
+		seen[key]++
+		if n := seen[key]; n > 1 {
+			name = fmt.Sprintf("%s#%d", name, n)
+		}
+		cyclo.add(float64(stats.CyclomaticComplexity), stats.PackagePath, name, file)
+		maint.add(float64(stats.MaintenabilityIndex), stats.PackagePath, name, file)
+		difficulty.add(stats.HalsbreadDifficulty, stats.PackagePath, name, file)
+		volume.add(stats.HalsbreadVolume, stats.PackagePath, name, file)
+		loc.add(float64(stats.LOC), stats.PackagePath, name, file)
+	}
+
+	pkgLabels := []string{"package"}
+	functions := &openmetricsFamily{Name: "package_functions", Help: "Number of analyzed functions of the package.", Labels: pkgLabels}
+	pkgLoc := &openmetricsFamily{Name: "package_loc", Help: "Lines of code of all analyzed functions of the package.", Labels: pkgLabels}
+	avgCyclo := &openmetricsFamily{Name: "package_cyclomatic_avg", Help: "Average cyclomatic complexity of the package functions.", Labels: pkgLabels}
+	maxCyclo := &openmetricsFamily{Name: "package_cyclomatic_max", Help: "Highest cyclomatic complexity of the package functions.", Labels: pkgLabels}
+	avgMaint := &openmetricsFamily{Name: "package_maintainability_index_avg", Help: "Average maintainability index of the package functions.", Labels: pkgLabels}
+	minMaint := &openmetricsFamily{Name: "package_maintainability_index_min", Help: "Lowest maintainability index of the package functions.", Labels: pkgLabels}
+	tooComplex := &openmetricsFamily{Name: "package_too_complex_functions", Help: "Number of package functions over the cyclomatic complexity threshold.", Labels: pkgLabels}
+	notMaintainable := &openmetricsFamily{Name: "package_not_maintainable_functions", Help: "Number of package functions under the maintainability index threshold.", Labels: pkgLabels}
+	for _, p := range toPackageStats(arr) {
+		functions.add(float64(p.Functions), p.Package)
+		pkgLoc.add(float64(p.LOC), p.Package)
+		avgCyclo.add(p.AvgCyclo(), p.Package)
+		maxCyclo.add(float64(p.MaxCyclo), p.Package)
+		avgMaint.add(p.AvgMaint(), p.Package)
+		minMaint.add(float64(p.MinMaint), p.Package)
+		tooComplex.add(float64(p.TooComplex), p.Package)
+		notMaintainable.add(float64(p.NotMaintainable), p.Package)
+	}
+
+	return []*openmetricsFamily{cyclo, maint, difficulty, volume, loc,
+		functions, pkgLoc, avgCyclo, maxCyclo, avgMaint, minMaint, tooComplex, notMaintainable}
+}
+
+// doPrintOpenmetrics writes gauges in Prometheus text exposition format,
+// terminated by OpenMetrics "# EOF" which Prometheus parsers take as a comment.
+func doPrintOpenmetrics(w io.Writer, arr []complexity.FuncStatsType) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range toOpenmetricsFamilies(arr) {
+		name := openmetricsPrefix + f.Name
+		fmt.Fprintf(bw, "# HELP %s %s\n", name, f.Help)
+		fmt.Fprintf(bw, "# TYPE %s gauge\n", name)
+		for _, s := range f.Samples {
+			labels := make([]string, len(f.Labels))
+			for i, l := range f.Labels {
+				labels[i] = fmt.Sprintf(`%s="%s"`, l, escapeOpenmetricsLabel(s.LabelValues[i]))
+			}
+			fmt.Fprintf(bw, "%s{%s} %s\n", name, strings.Join(labels, ","), formatOpenmetricsValue(s.Value))
+		}
+	}
+	fmt.Fprintln(bw, "# EOF")
+	return bw.Flush()
+}
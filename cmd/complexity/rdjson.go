@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+type rdjsonSourceTag struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type rdjsonPositionTag struct {
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+}
+
+type rdjsonRangeTag struct {
+	Start rdjsonPositionTag  `json:"start"`
+	End   *rdjsonPositionTag `json:"end,omitempty"`
+}
+
+type rdjsonLocationTag struct {
+	Path  string         `json:"path"`
+	Range rdjsonRangeTag `json:"range"`
+}
+
+type rdjsonCodeTag struct {
+	Value string `json:"value"`
+	URL   string `json:"url,omitempty"`
+}
+
+// rdjsonDiagnosticTag is single reviewdog diagnostic
+type rdjsonDiagnosticTag struct {
+	Message  string            `json:"message"`
+	Location rdjsonLocationTag `json:"location"`
+	Severity string            `json:"severity"`
+	Source   rdjsonSourceTag   `json:"source"`
+	Code     rdjsonCodeTag     `json:"code"`
+}
+
+// rdjsonResultTag is reviewdog diagnostic result, the rdjson document
+type rdjsonResultTag struct {
+	Source      rdjsonSourceTag       `json:"source"`
+	Severity    string                `json:"severity"`
+	Diagnostics []rdjsonDiagnosticTag `json:"diagnostics"`
+}
+
+// toRdjsonSeverity maps violation severity onto one of ERROR, WARNING, INFO
+func toRdjsonSeverity(severity string) string {
+	switch severity {
+	case severityError:
+		return "ERROR"
+	case severityWarning:
+		return "WARNING"
+	default:
+		return "INFO"
+	}
+}
+
+func toRdjsonSource() rdjsonSourceTag {
+	return rdjsonSourceTag{Name: complexity.Analyzer.Name, URL: toolInfoURI}
+}
+
+// toRdjsonDiagnostics ranges each crossed threshold over the whole function
+func toRdjsonDiagnostics(arr []complexity.FuncStatsType) []rdjsonDiagnosticTag {
+	diags := []rdjsonDiagnosticTag{}
+	for _, stats := range sortedFuncStats(arr) {
+		for _, v := range violationsOf(stats) {
+			rng := rdjsonRangeTag{Start: rdjsonPositionTag{Line: stats.Line}}
+			if stats.EndLine > 0 {
+				rng.End = &rdjsonPositionTag{Line: stats.EndLine}
+			}
+			diags = append(diags, rdjsonDiagnosticTag{
+				Message: v.Message,
+				Location: rdjsonLocationTag{
+					Path:  filepath.ToSlash(getRelativeFileName(stats.Filename, currDir)),
+					Range: rng,
+				},
+				Severity: toRdjsonSeverity(v.Severity),
+				Source:   toRdjsonSource(),
+				Code:     rdjsonCodeTag{Value: v.Metric, URL: toMetricHelpURI(metrics[metricIndex(v.Metric)])},
+			})
+		}
+	}
+	return diags
+}
+
+func doPrintRdjson(w io.Writer, arr []complexity.FuncStatsType) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rdjsonResultTag{
+		Source:      toRdjsonSource(),
+		Severity:    "WARNING",
+		Diagnostics: toRdjsonDiagnostics(arr),
+	})
+}
+
+// doPrintRdjsonl writes one diagnostic per line
+func doPrintRdjsonl(w io.Writer, arr []complexity.FuncStatsType) error {
+	enc := json.NewEncoder(w)
+	for _, d := range toRdjsonDiagnostics(arr) {
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+const (
+	sarifVersion   = "2.1.0"
+	sarifSchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifSrcRoot   = "%SRCROOT%"
+	toolInfoURI    = "https://github.com/fikin/go-complexity-analysis"
+)
+
+type sarifMessageTag struct {
+	Text string `json:"text"`
+}
+
+type sarifRuleConfigTag struct {
+	Level string `json:"level"`
+}
+
+type sarifRulePropertiesTag struct {
+	Threshold int `json:"threshold"`
+}
+
+type sarifRuleTag struct {
+	ID                   string                 `json:"id"`
+	Name                 string                 `json:"name"`
+	ShortDescription     sarifMessageTag        `json:"shortDescription"`
+	HelpURI              string                 `json:"helpUri"`
+	DefaultConfiguration sarifRuleConfigTag     `json:"defaultConfiguration"`
+	Properties           sarifRulePropertiesTag `json:"properties"`
+}
+
+type sarifDriverTag struct {
+	Name           string         `json:"name"`
+	Version        string         `json:"version"`
+	InformationURI string         `json:"informationUri"`
+	Rules          []sarifRuleTag `json:"rules"`
+}
+
+type sarifToolTag struct {
+	Driver sarifDriverTag `json:"driver"`
+}
+
+type sarifArtifactLocationTag struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegionTag struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine,omitempty"`
+}
+
+type sarifPhysicalLocationTag struct {
+	ArtifactLocation sarifArtifactLocationTag `json:"artifactLocation"`
+	Region           sarifRegionTag           `json:"region"`
+}
+
+type sarifLogicalLocationTag struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+type sarifLocationTag struct {
+	PhysicalLocation sarifPhysicalLocationTag  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocationTag `json:"logicalLocations"`
+}
+
+// sarifResultPropertiesTag carries all metric values of the function
+type sarifResultPropertiesTag struct {
+	Threshold            int     `json:"threshold"`
+	CyclomaticComplexity int     `json:"cyclomaticComplexity"`
+	MaintainabilityIndex int     `json:"maintainabilityIndex"`
+	HalsteadDifficulty   float64 `json:"halsteadDifficulty"`
+	HalsteadVolume       float64 `json:"halsteadVolume"`
+	TimeToCode           float64 `json:"timeToCode"`
+	LOC                  int     `json:"loc"`
+	ConstantsLOC         int     `json:"constantsLoc"`
+}
+
+type sarifResultTag struct {
+	RuleID     string                   `json:"ruleId"`
+	RuleIndex  int                      `json:"ruleIndex"`
+	Level      string                   `json:"level"`
+	Message    sarifMessageTag          `json:"message"`
+	Locations  []sarifLocationTag       `json:"locations"`
+	Properties sarifResultPropertiesTag `json:"properties"`
+}
+
+type sarifURIBaseTag struct {
+	URI string `json:"uri"`
+}
+
+type sarifRunTag struct {
+	Tool               sarifToolTag               `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifURIBaseTag `json:"originalUriBaseIds"`
+	Results            []sarifResultTag           `json:"results"`
+}
+
+// sarifLogTag is structure used to serialize in SARIF all threshold violations
+type sarifLogTag struct {
+	Schema  string        `json:"$schema"`
+	Version string        `json:"version"`
+	Runs    []sarifRunTag `json:"runs"`
+}
+
+// toSarifLevel maps violation severity onto one of error, warning, note
+func toSarifLevel(severity string) string {
+	if severity == severityInfo {
+		return "note"
+	}
+	return severity
+}
+
+// toMetricHelpURI links the README section describing the metric
+func toMetricHelpURI(m metricType) string {
+	return toolInfoURI + "#" + strings.ReplaceAll(strings.ToLower(m.Title), " ", "-")
+}
+
+func toSarifRules() []sarifRuleTag {
+	thresholds := map[string]int{
+		metricCyclomatic:      complexity.CycloOver,
+		metricMaintainability: complexity.MaintUnder,
+	}
+	rules := []sarifRuleTag{}
+	for _, m := range metrics {
+		rules = append(rules, sarifRuleTag{
+			ID:                   m.Name,
+			Name:                 m.RuleName,
+			ShortDescription:     sarifMessageTag{Text: m.Description},
+			HelpURI:              toMetricHelpURI(m),
+			DefaultConfiguration: sarifRuleConfigTag{Level: toSarifLevel(m.Severity)},
+			Properties:           sarifRulePropertiesTag{Threshold: thresholds[m.Name]},
+		})
+	}
+	return rules
+}
+
+// toSarifArtifactLocation refers files under the current directory relative to %SRCROOT%
+func toSarifArtifactLocation(filename string) sarifArtifactLocationTag {
+	fn := getRelativeFileName(filename, currDir)
+	if filepath.IsAbs(fn) {
+		return sarifArtifactLocationTag{URI: toFileURI(fn)}
+	}
+	return sarifArtifactLocationTag{URI: filepath.ToSlash(fn), URIBaseID: sarifSrcRoot}
+}
+
+func toFileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return "file://" + path
+}
+
+func toSarifLogicalLocationKind(stats complexity.FuncStatsType) string {
+	if stats.Receiver != "" {
+		return "member"
+	}
+	return "function"
+}
+
+func toSarifLog(arr []complexity.FuncStatsType) sarifLogTag {
+	run := sarifRunTag{
+		Tool: sarifToolTag{Driver: sarifDriverTag{
+			Name:           complexity.Analyzer.Name,
+			Version:        toolVersion(),
+			InformationURI: toolInfoURI,
+			Rules:          toSarifRules(),
+		}},
+		OriginalURIBaseIDs: map[string]sarifURIBaseTag{
+			sarifSrcRoot: {URI: toFileURI(currDir) + "/"},
+		},
+		Results: []sarifResultTag{},
+	}
+	for _, stats := range sortedFuncStats(arr) {
+		for _, v := range violationsOf(stats) {
+			run.Results = append(run.Results, sarifResultTag{
+				RuleID:    v.Metric,
+				RuleIndex: metricIndex(v.Metric),
+				Level:     toSarifLevel(v.Severity),
+				Message:   sarifMessageTag{Text: v.Message},
+				Locations: []sarifLocationTag{{
+					PhysicalLocation: sarifPhysicalLocationTag{
+						ArtifactLocation: toSarifArtifactLocation(stats.Filename),
+						Region:           sarifRegionTag{StartLine: stats.Line, EndLine: stats.EndLine},
+					},
+					LogicalLocations: []sarifLogicalLocationTag{{
+						Name:               stats.FunctionName,
+						FullyQualifiedName: stats.QualifiedName(),
+						Kind:               toSarifLogicalLocationKind(stats),
+					}},
+				}},
+				Properties: sarifResultPropertiesTag{
+					Threshold:            v.Threshold,
+					CyclomaticComplexity: stats.CyclomaticComplexity,
+					MaintainabilityIndex: stats.MaintenabilityIndex,
+					HalsteadDifficulty:   stats.HalsbreadDifficulty,
+					HalsteadVolume:       stats.HalsbreadVolume,
+					TimeToCode:           stats.TimeToCode,
+					LOC:                  stats.LOC,
+					ConstantsLOC:         stats.ConstantsLOC,
+				},
+			})
+		}
+	}
+	return sarifLogTag{Schema: sarifSchemaURI, Version: sarifVersion, Runs: []sarifRunTag{run}}
+}
+
+func doPrintSarif(w io.Writer, arr []complexity.FuncStatsType) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(toSarifLog(arr))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"path/filepath"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+type sonarTextRangeTag struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine,omitempty"`
+}
+
+type sonarLocationTag struct {
+	Message   string            `json:"message"`
+	FilePath  string            `json:"filePath"`
+	TextRange sonarTextRangeTag `json:"textRange"`
+}
+
+// sonarIssueTag is single issue of SonarQube generic issue import format
+type sonarIssueTag struct {
+	EngineID        string           `json:"engineId"`
+	RuleID          string           `json:"ruleId"`
+	Severity        string           `json:"severity"`
+	Type            string           `json:"type"`
+	PrimaryLocation sonarLocationTag `json:"primaryLocation"`
+	EffortMinutes   int              `json:"effortMinutes,omitempty"`
+}
+
+type sonarReportTag struct {
+	Issues []sonarIssueTag `json:"issues"`
+}
+
+// toSonarSeverity maps violation severity onto one of INFO, MINOR, MAJOR, CRITICAL, BLOCKER
+func toSonarSeverity(severity string) string {
+	switch severity {
+	case severityError:
+		return "MAJOR"
+	case severityWarning:
+		return "MINOR"
+	default:
+		return "INFO"
+	}
+}
+
+// toSonarEffort estimates remediation effort as Halstead time to code, at least a minute
+func toSonarEffort(stats complexity.FuncStatsType) int {
+	return int(math.Max(1, math.Ceil(stats.TimeToCode*60)))
+}
+
+func toSonarReport(arr []complexity.FuncStatsType) sonarReportTag {
+	report := sonarReportTag{Issues: []sonarIssueTag{}}
+	for _, stats := range sortedFuncStats(arr) {
+		for _, v := range violationsOf(stats) {
+			report.Issues = append(report.Issues, sonarIssueTag{
+				EngineID: complexity.Analyzer.Name,
+				RuleID:   v.Metric,
+				Severity: toSonarSeverity(v.Severity),
+				Type:     "CODE_SMELL",
+				PrimaryLocation: sonarLocationTag{
+					Message:   v.Message,
+					FilePath:  filepath.ToSlash(getRelativeFileName(stats.Filename, currDir)),
+					TextRange: sonarTextRangeTag{StartLine: stats.Line, EndLine: stats.EndLine},
+				},
+				EffortMinutes: toSonarEffort(stats),
+			})
+		}
+	}
+	return report
+}
+
+func doPrintSonar(w io.Writer, arr []complexity.FuncStatsType) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(toSonarReport(arr))
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// stdinArg is the argument reading the source file from stdin in --syntax-only mode
+const stdinArg = "-"
+
+// stdinFileName is the file name source read from stdin is reported with
+const stdinFileName = "<stdin>"
+
+// parseAndAnalyze analyzes the files matching the args parsed with go/parser only, neither loading
+// dependencies nor type checking. Syntax errors are printed, the code around them is analyzed still.
+func parseAndAnalyze(args []string, analyzer *analysis.Analyzer) ([]foundDiagnosticsStruct, error) {
+	fns, err := listSyntaxFiles(args)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := parseSyntaxPackages(fns, os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	packages.PrintErrors(pkgs)
+	return analyze(pkgs, []*analysis.Analyzer{runningDespiteErrors(analyzer)})
+}
+
+// listSyntaxFiles returns the .go files of the args, each one being "-" for stdin, a file,
+// a directory or a directory tree like "./...". Directory files are filtered by build constraints and tests config.
+func listSyntaxFiles(args []string) ([]string, error) {
+	ctx := build.Default
+	ctx.BuildTags = theConfig.Run.BuildTags
+	fns := []string{}
+	for _, arg := range args {
+		if arg == stdinArg {
+			fns = append(fns, stdinArg)
+			continue
+		}
+		dir, isTree := strings.CutSuffix(arg, "/...")
+		if dir == "" { // "/..."
+			dir = "/"
+		}
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			fns = append(fns, dir)
+			continue
+		}
+		dirs := []string{dir}
+		if isTree {
+			if dirs, err = listSourceDirs(dir); err != nil {
+				return nil, err
+			}
+		}
+		for _, d := range dirs {
+			found, err := listDirGoFiles(&ctx, d)
+			if err != nil {
+				return nil, err
+			}
+			fns = append(fns, found...)
+		}
+	}
+	if len(fns) == 0 {
+		return nil, fmt.Errorf("%s matched no files", strings.Join(args, " "))
+	}
+	return fns, nil
+}
+
+// listSourceDirs returns the directory and its sub-directories the go tool matches with "dir/...",
+// i.e. without testdata, vendor, hidden and nested module directories
+func listSourceDirs(root string) ([]string, error) {
+	dirs := []string{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if p != root {
+			name := d.Name()
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		dirs = append(dirs, p)
+		return nil
+	})
+	return dirs, err
+}
+
+// listDirGoFiles returns the directory .go files matching the build context
+func listDirGoFiles(ctx *build.Context, dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fns := []string{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || (!theConfig.Run.Tests && strings.HasSuffix(name, "_test.go")) {
+			continue
+		}
+		if ok, err := ctx.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		fns = append(fns, filepath.Join(dir, name))
+	}
+	return fns, nil
+}
+
+// syntaxPackageKeyType tells packages apart, e.g. a package and its external tests share the directory
+type syntaxPackageKeyType struct {
+	Dir  string
+	Name string
+}
+
+// parseSyntaxPackages parses the files into packages by directory and package name.
+// Packages are not type checked, their Types is empty and TypesInfo has no entries.
+func parseSyntaxPackages(fns []string, stdin io.Reader) ([]*packages.Package, error) {
+	fset := token.NewFileSet()
+	byKey := map[syntaxPackageKeyType]*packages.Package{}
+	keys := []syntaxPackageKeyType{}
+	modules := map[string]*packages.Module{} // by directory
+	for _, fn := range fns {
+		src, filename, dir, err := readSyntaxFile(fn, stdin)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+		if f == nil {
+			return nil, err
+		}
+		key := syntaxPackageKeyType{Dir: dir, Name: f.Name.Name}
+		pkg, ok := byKey[key]
+		if !ok {
+			pkg = newSyntaxPackage(fset, key, modules)
+			byKey[key] = pkg
+			keys = append(keys, key)
+		}
+		pkg.GoFiles = append(pkg.GoFiles, filename)
+		pkg.CompiledGoFiles = append(pkg.CompiledGoFiles, filename)
+		pkg.Syntax = append(pkg.Syntax, f)
+		if errs, ok := err.(scanner.ErrorList); ok {
+			for _, e := range errs {
+				pkg.Errors = append(pkg.Errors, packages.Error{Pos: e.Pos.String(), Msg: e.Msg, Kind: packages.ParseError})
+			}
+		} else if err != nil {
+			pkg.Errors = append(pkg.Errors, packages.Error{Pos: filename, Msg: err.Error(), Kind: packages.ParseError})
+		}
+	}
+
+	pkgs := []*packages.Package{}
+	for _, key := range keys {
+		pkgs = append(pkgs, byKey[key])
+	}
+	return pkgs, nil
+}
+
+// readSyntaxFile returns the file content, absolute name and directory, the current one for stdin
+func readSyntaxFile(fn string, stdin io.Reader) (src []byte, filename string, dir string, err error) {
+	if fn == stdinArg {
+		src, err = io.ReadAll(stdin)
+		return src, stdinFileName, currDir, err
+	}
+	if filename, err = filepath.Abs(fn); err != nil {
+		return nil, "", "", err
+	}
+	src, err = os.ReadFile(filename)
+	return src, filename, filepath.Dir(filename), err
+}
+
+func newSyntaxPackage(fset *token.FileSet, key syntaxPackageKeyType, modules map[string]*packages.Module) *packages.Package {
+	mod, ok := modules[key.Dir]
+	if !ok {
+		mod = findModule(key.Dir)
+		modules[key.Dir] = mod
+	}
+	pkgPath := filepath.ToSlash(getRelativeFileName(key.Dir, currDir))
+	if mod != nil {
+		rel, _ := filepath.Rel(mod.Dir, key.Dir)
+		pkgPath = path.Join(mod.Path, filepath.ToSlash(rel))
+	}
+	if strings.HasSuffix(key.Name, "_test") {
+		pkgPath += "_test" // external tests
+	}
+	return &packages.Package{
+		ID:         pkgPath,
+		Name:       key.Name,
+		PkgPath:    pkgPath,
+		Module:     mod,
+		Fset:       fset,
+		Types:      types.NewPackage(pkgPath, key.Name),
+		TypesInfo:  &types.Info{},
+		TypesSizes: types.SizesFor("gc", runtime.GOARCH),
+		IllTyped:   true, // only analyzers running despite errors are run, see runningDespiteErrors
+	}
+}
+
+// findModule returns the module of the go.mod file in the directory or the nearest parent one, nil if none
+func findModule(dir string) *packages.Module {
+	for {
+		gomod := filepath.Join(dir, "go.mod")
+		if buf, err := os.ReadFile(gomod); err == nil {
+			return &packages.Module{Path: modfile.ModulePath(buf), Dir: dir, GoMod: gomod}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
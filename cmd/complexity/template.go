@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"text/template"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// outTemplateData is what user supplied output templates are rendered with
+type outTemplateData struct {
+	Tool       string
+	Version    string
+	GoVersion  string
+	CycloOver  int
+	MaintUnder int
+	// Functions are all analyzed functions, ordered by file and line
+	Functions []complexity.FuncStatsType
+	// Violations are all crossed thresholds, ordered by file and line
+	Violations []outTemplateViolation
+	Packages   []packageStatsType
+}
+
+// outTemplateViolation is crossed threshold together with its function
+type outTemplateViolation struct {
+	violationType
+	Func complexity.FuncStatsType
+}
+
+// outTemplateFuncs are helper functions available to output templates, in addition to text/template builtins like printf
+var outTemplateFuncs = template.FuncMap{
+	// relpath is file name relative to the current directory, slash separated
+	"relpath": func(filename string) string {
+		return filepath.ToSlash(getRelativeFileName(filename, currDir))
+	},
+	// severity is the highest severity of the function's violations, "" when none
+	"severity": func(stats complexity.FuncStatsType) string {
+		severity := ""
+		for _, v := range violationsOf(stats) {
+			if severity == "" || severityRank(v.Severity) > severityRank(severity) {
+				severity = v.Severity
+			}
+		}
+		return severity
+	},
+	"violations": violationsOf,
+}
+
+// loadOutTemplate parses template from the file or the inline text, exactly one of them given
+func loadOutTemplate(filename string, text string) (*template.Template, error) {
+	if filename != "" && text != "" {
+		return nil, errors.New("only one of -out-template and -out-template-string can be given")
+	}
+	name := "out-template-string"
+	if filename != "" {
+		buf, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		name, text = filepath.Base(filename), string(buf)
+	}
+	return template.New(name).Funcs(outTemplateFuncs).Parse(text)
+}
+
+func toOutTemplateData(arr []complexity.FuncStatsType) outTemplateData {
+	data := outTemplateData{
+		Tool:       complexity.Analyzer.Name,
+		Version:    toolVersion(),
+		GoVersion:  runtime.Version(),
+		CycloOver:  complexity.CycloOver,
+		MaintUnder: complexity.MaintUnder,
+		Functions:  sortedFuncStats(arr),
+		Violations: []outTemplateViolation{},
+		Packages:   toPackageStats(arr),
+	}
+	for _, stats := range data.Functions {
+		for _, v := range violationsOf(stats) {
+			data.Violations = append(data.Violations, outTemplateViolation{violationType: v, Func: stats})
+		}
+	}
+	return data
+}
+
+func doPrintOutTemplate(w io.Writer, tmpl *template.Template, arr []complexity.FuncStatsType) error {
+	return tmpl.Execute(w, toOutTemplateData(arr))
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+	"golang.org/x/tools/go/analysis"
+)
+
+// treemap colouring metrics
+const (
+	treemapColorMaint = "mi"
+	treemapColorCyclo = "cyclo"
+)
+
+// treemap layout constants, in svg pixels
+const (
+	treemapLabelHeight = 14
+	treemapPadding     = 2
+	treemapLegendSpace = 24
+)
+
+var treemapBandFills = map[string]string{
+	bandGreen:  "#81c784",
+	bandYellow: "#fff176",
+	bandRed:    "#e57373",
+}
+
+var treemapContainerFills = []string{"#eeeeee", "#e0e0e0", "#d0d0d0", "#c0c0c0"}
+
+// treemapNode is module, package, file or function, the latter being leafs
+type treemapNode struct {
+	Label    string
+	Title    string
+	LOC      int
+	Fill     string
+	Children []*treemapNode
+}
+
+func (n *treemapNode) child(label string) *treemapNode {
+	for _, c := range n.Children {
+		if c.Label == label {
+			return c
+		}
+	}
+	c := &treemapNode{Label: label, Title: label}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// treemapRect is laid out node
+type treemapRect struct {
+	X, Y, W, H float64
+	Depth      int
+	Node       *treemapNode
+}
+
+func toTreemapFill(stats complexity.FuncStatsType, colorBy string) string {
+	if colorBy == treemapColorCyclo {
+		return treemapBandFills[cycloBand(stats.CyclomaticComplexity)]
+	}
+	return treemapBandFills[maintBand(stats.MaintenabilityIndex)]
+}
+
+// buildTreemap nests functions as module -> package -> file -> function, sized by LOC
+func buildTreemap(arr []complexity.FuncStatsType, colorBy string) *treemapNode {
+	root := &treemapNode{}
+	for _, stats := range sortedFuncStats(arr) {
+		mod := root.child(stats.ModulePath)
+		pkgLabel := strings.TrimPrefix(strings.TrimPrefix(stats.PackagePath, stats.ModulePath), "/")
+		if pkgLabel == "" {
+			pkgLabel = filepath.Base(stats.PackagePath)
+		}
+		pkg := mod.child(pkgLabel)
+		pkg.Title = stats.PackagePath
+		file := pkg.child(filepath.Base(stats.Filename))
+		file.Title = getRelativeFileName(stats.Filename, currDir)
+		file.Children = append(file.Children, &treemapNode{
+			Label: stats.LocalName(),
+			Title: fmt.Sprintf("%s\n%s:%d\nloc: %d, cyclomatic complexity: %d, maintainability index: %d",
+				stats.QualifiedName(), getRelativeFileName(stats.Filename, currDir), stats.Line,
+				stats.LOC, stats.CyclomaticComplexity, stats.MaintenabilityIndex),
+			LOC:  stats.LOC,
+			Fill: toTreemapFill(stats, colorBy),
+		})
+	}
+	sumTreemapLOC(root)
+	if len(root.Children) == 1 { // single module is the root itself
+		root = root.Children[0]
+	}
+	return root
+}
+
+func sumTreemapLOC(n *treemapNode) int {
+	if len(n.Children) == 0 {
+		return n.LOC
+	}
+	n.LOC = 0
+	for _, c := range n.Children {
+		n.LOC += sumTreemapLOC(c)
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool { return n.Children[i].LOC > n.Children[j].LOC })
+	return n.LOC
+}
+
+// layoutTreemap places the node and its descendants into the rectangle
+func layoutTreemap(n *treemapNode, r treemapRect, depth int, out []treemapRect) []treemapRect {
+	r.Depth, r.Node = depth, n
+	out = append(out, r)
+	if len(n.Children) == 0 || n.LOC == 0 {
+		return out
+	}
+	inner := r
+	if depth > 0 {
+		inner.X += treemapPadding
+		inner.W -= 2 * treemapPadding
+		inner.Y += treemapPadding
+		inner.H -= 2 * treemapPadding
+		if inner.H > 2*treemapLabelHeight {
+			inner.Y += treemapLabelHeight
+			inner.H -= treemapLabelHeight
+		}
+	}
+	if inner.W <= 0 || inner.H <= 0 {
+		return out
+	}
+	scale := inner.W * inner.H / float64(n.LOC)
+	areas := make([]float64, len(n.Children))
+	for i, c := range n.Children {
+		areas[i] = float64(c.LOC) * scale
+	}
+	for i, cr := range squarify(areas, inner) {
+		out = layoutTreemap(n.Children[i], cr, depth+1, out)
+	}
+	return out
+}
+
+// squarify lays out areas, sorted in descending order, into the rectangle
+// following the squarified treemap algorithm of Bruls, Huizing and van Wijk.
+func squarify(areas []float64, r treemapRect) []treemapRect {
+	out := make([]treemapRect, 0, len(areas))
+	for len(areas) > 0 {
+		short := math.Min(r.W, r.H)
+		n := 1
+		for n < len(areas) && worstAspect(areas[:n+1], short) <= worstAspect(areas[:n], short) {
+			n++
+		}
+		sum := 0.0
+		for _, a := range areas[:n] {
+			sum += a
+		}
+		if r.W >= r.H {
+			w := sum / r.H
+			y := r.Y
+			for _, a := range areas[:n] {
+				out = append(out, treemapRect{X: r.X, Y: y, W: w, H: a / w})
+				y += a / w
+			}
+			r.X += w
+			r.W -= w
+		} else {
+			h := sum / r.W
+			x := r.X
+			for _, a := range areas[:n] {
+				out = append(out, treemapRect{X: x, Y: r.Y, W: a / h, H: h})
+				x += a / h
+			}
+			r.Y += h
+			r.H -= h
+		}
+		areas = areas[n:]
+	}
+	return out
+}
+
+// worstAspect is the highest aspect ratio among row of areas laid along side of given length
+func worstAspect(row []float64, side float64) float64 {
+	sum, max, min := 0.0, 0.0, math.MaxFloat64
+	for _, a := range row {
+		sum += a
+		max = math.Max(max, a)
+		min = math.Min(min, a)
+	}
+	return math.Max(side*side*max/(sum*sum), sum*sum/(side*side*min))
+}
+
+func escapeXML(s string) string {
+	sb := strings.Builder{}
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// fitLabel truncates the label to fit into the width, assuming ~7px per character
+func fitLabel(label string, width float64) string {
+	n, runes := int(width/7), []rune(label)
+	if n < 3 {
+		return ""
+	}
+	if len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return label
+}
+
+func doPrintTreemap(w io.Writer, arr []complexity.FuncStatsType, colorBy string, width, height int) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		width, height+treemapLegendSpace, width, height+treemapLegendSpace)
+	rects := layoutTreemap(buildTreemap(arr, colorBy), treemapRect{W: float64(width), H: float64(height)}, 0, nil)
+	for _, r := range rects {
+		fill := r.Node.Fill
+		if fill == "" {
+			fill = treemapContainerFills[r.Depth%len(treemapContainerFills)]
+		}
+		fmt.Fprintf(bw, `<g><title>%s</title><rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" stroke="#ffffff"/>`,
+			escapeXML(r.Node.Title), r.X, r.Y, r.W, r.H, fill)
+		label := fitLabel(r.Node.Label, r.W-2*treemapPadding)
+		if label != "" && r.Depth > 0 && r.H > treemapLabelHeight {
+			fmt.Fprintf(bw, `<text x="%.1f" y="%.1f">%s</text>`, r.X+treemapPadding, r.Y+treemapLabelHeight-2, escapeXML(label))
+		}
+		fmt.Fprintln(bw, "</g>")
+	}
+	metric, bands := "maintainability index", []string{"0-9", "10-19", "20-100"}
+	fills := []string{treemapBandFills[bandRed], treemapBandFills[bandYellow], treemapBandFills[bandGreen]}
+	if colorBy == treemapColorCyclo {
+		metric, bands = "cyclomatic complexity", []string{"0-10", "11+"}
+		fills = []string{treemapBandFills[bandGreen], treemapBandFills[bandRed]}
+	}
+	fmt.Fprintf(bw, `<text x="4" y="%d">area: lines of code, colour: %s</text>`+"\n", height+16, metric)
+	for i, b := range bands {
+		x := 300 + i*70
+		fmt.Fprintf(bw, `<rect x="%d" y="%d" width="14" height="14" fill="%s"/><text x="%d" y="%d">%s</text>`+"\n",
+			x, height+5, fills[i], x+18, height+16, b)
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// parseInterspersed parses flags given before, between and after positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// runTreemap implements "complexity treemap [-o out.svg] [-color mi|cyclo] [package]"
+func runTreemap(args []string, analyzer *analysis.Analyzer) (exitcode int) {
+	fs := flag.NewFlagSet("treemap", flag.ExitOnError)
+	out := fs.String("o", "", "svg file to write, stdout if not given")
+	colorBy := fs.String("color", treemapColorMaint, "colour functions by maintainability index 'mi' or cyclomatic complexity 'cyclo'")
+	width := fs.Int("width", 1200, "image width in pixels")
+	height := fs.Int("height", 800, "treemap height in pixels, the legend is added below")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s treemap [-flag] [package]\n\nFlags:\n", analyzer.Name)
+		fs.PrintDefaults()
+	}
+	patterns, err := parseInterspersed(fs, args)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	if len(patterns) == 0 || (*colorBy != treemapColorMaint && *colorBy != treemapColorCyclo) {
+		fs.Usage()
+		return 1
+	}
+
+	found, err := loadAndAnalyze(patterns, analyzer)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	arr := funcStatsOf(found)
+
+	err = writeOutput(*out, func(w io.Writer) error {
+		return doPrintTreemap(w, arr, *colorBy, *width, *height)
+	})
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// txtLineType is diagnostic with its position resolved
+type txtLineType struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+// toTxtLines resolves diagnostics positions through package file sets, ordered by file, line and column
+func toTxtLines(arr []foundDiagnosticsStruct) []txtLineType {
+	lines := []txtLineType{}
+	for _, f := range arr {
+		for _, d := range f.diagnostics {
+			pos := f.pkg.Fset.Position(d.Pos)
+			lines = append(lines, txtLineType{
+				File:   getRelativeFileName(pos.Filename, currDir),
+				Line:   pos.Line,
+				Column: pos.Column,
+				Msg:    strings.TrimSpace(d.Message),
+			})
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		if lines[i].File != lines[j].File {
+			return lines[i].File < lines[j].File
+		}
+		if lines[i].Line != lines[j].Line {
+			return lines[i].Line < lines[j].Line
+		}
+		return lines[i].Column < lines[j].Column
+	})
+	return lines
+}
+
+// doPrintTxt prints go vet like "file:line:col: message" lines followed by summary
+func doPrintTxt(w io.Writer, stats []complexity.FuncStatsType, arr []foundDiagnosticsStruct) error {
+	bw := bufio.NewWriter(w)
+	lines := toTxtLines(arr)
+	for _, l := range lines {
+		fmt.Fprintf(bw, "%s:%d:%d: %s\n", l.File, l.Line, l.Column, l.Msg)
+	}
+
+	if len(lines) > 0 {
+		fmt.Fprintln(bw)
+	}
+	fmt.Fprintln(bw, txtSummary(stats))
+	return bw.Flush()
+}
+
+// txtSummary returns the number of functions and thresholds they cross, per metric
+func txtSummary(stats []complexity.FuncStatsType) string {
+	perMetric := make([]int, len(metrics))
+	total := 0
+	for _, s := range stats {
+		for _, v := range violationsOf(s) {
+			perMetric[metricIndex(v.Metric)]++
+			total++
+		}
+	}
+	counts := make([]string, len(metrics))
+	for i, m := range metrics {
+		counts[i] = fmt.Sprintf("%d %s", perMetric[i], strings.ToLower(m.Title))
+	}
+	return fmt.Sprintf("%d functions analyzed, %d thresholds crossed: %s.", len(stats), total, strings.Join(counts, ", "))
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// metric names as used in rule ids and reports
+const (
+	metricCyclomatic      = "cyclomatic"
+	metricMaintainability = "maintainability"
+)
+
+// colour bands of metric values, see README
+const (
+	bandGreen  = "green"
+	bandYellow = "yellow"
+	bandRed    = "red"
+)
+
+// band bounds of metric values, see README
+const (
+	cycloRedOver     = 10
+	maintRedUnder    = 10
+	maintYellowUnder = 20
+)
+
+// severity levels of violations, weakest last
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// severityRank orders severities, higher being worse, 0 for unknown ones
+func severityRank(severity string) int {
+	switch severity {
+	case severityError:
+		return 3
+	case severityWarning:
+		return 2
+	case severityInfo:
+		return 1
+	}
+	return 0
+}
+
+// severityTierType overrides the severity of violations with values beyond the bound
+type severityTierType struct {
+	Bound    int
+	Severity string
+}
+
+// severity tiers of cyclomatic complexity (value > bound) and maintainability index (value < bound) violations,
+// most extreme bound first, the first matching one applies
+var (
+	cycloSeverityTiers = []severityTierType{}
+	maintSeverityTiers = []severityTierType{{Bound: maintRedUnder, Severity: severityError}}
+)
+
+// tierSeverity returns the severity of the first tier the value is beyond, or the default one
+func tierSeverity(tiers []severityTierType, beyond func(bound int) bool, severity string) string {
+	for _, t := range tiers {
+		if beyond(t.Bound) {
+			return t.Severity
+		}
+	}
+	return severity
+}
+
+// metricType describes a metric functions are checked against
+type metricType struct {
+	Name        string
+	RuleName    string
+	Title       string
+	Description string
+	Severity    string // of violations unless raised by worse values
+}
+
+// metrics lists all metrics with thresholds, in reporting order
+var metrics = []metricType{
+	{
+		Name:        metricCyclomatic,
+		RuleName:    "CyclomaticComplexity",
+		Title:       "Cyclomatic complexity",
+		Description: "Function cyclomatic complexity is over the threshold.",
+		Severity:    severityError,
+	},
+	{
+		Name:        metricMaintainability,
+		RuleName:    "MaintainabilityIndex",
+		Title:       "Maintainability index",
+		Description: "Function maintainability index is under the threshold.",
+		Severity:    severityWarning,
+	},
+}
+
+// metricIndex returns the position of the metric in metrics
+func metricIndex(name string) int {
+	for i, m := range metrics {
+		if m.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// violationType is a single function crossing a single metric threshold
+type violationType struct {
+	Metric    string
+	Value     int
+	Threshold int
+	Severity  string
+	Message   string
+}
+
+// violationsOf returns all thresholds the function crosses, in metrics order
+func violationsOf(stats complexity.FuncStatsType) []violationType {
+	arr := []violationType{}
+	if stats.IsTooComplex {
+		arr = append(arr, violationType{
+			Metric:    metricCyclomatic,
+			Value:     stats.CyclomaticComplexity,
+			Threshold: complexity.CycloOver,
+			Severity: tierSeverity(cycloSeverityTiers, func(bound int) bool { return stats.CyclomaticComplexity > bound },
+				metrics[metricIndex(metricCyclomatic)].Severity),
+			Message: complexity.ToCycloDiagnosticMsg(stats),
+		})
+	}
+	if stats.IsNotMaintenable {
+		sev := tierSeverity(maintSeverityTiers, func(bound int) bool { return stats.MaintenabilityIndex < bound },
+			metrics[metricIndex(metricMaintainability)].Severity)
+		arr = append(arr, violationType{
+			Metric:    metricMaintainability,
+			Value:     stats.MaintenabilityIndex,
+			Threshold: complexity.MaintUnder,
+			Severity:  sev,
+			Message:   complexity.ToMaintDiagnosticMsg(stats),
+		})
+	}
+	return arr
+}
+
+// cycloBand returns the colour band of cyclomatic complexity value
+func cycloBand(v int) string {
+	if v > cycloRedOver {
+		return bandRed
+	}
+	return bandGreen
+}
+
+// maintBand returns the colour band of maintainability index value
+func maintBand(v int) string {
+	switch {
+	case v < maintRedUnder:
+		return bandRed
+	case v < maintYellowUnder:
+		return bandYellow
+	default:
+		return bandGreen
+	}
+}
+
+// funcBand returns the worst colour band of function metrics
+func funcBand(stats complexity.FuncStatsType) string {
+	c, m := cycloBand(stats.CyclomaticComplexity), maintBand(stats.MaintenabilityIndex)
+	if c == bandRed || m == bandRed {
+		return bandRed
+	}
+	return m
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fikin/go-complexity-analysis"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// terminal escape sequences of watch output
+const (
+	ansiClearScreen = "\033[H\033[2J"
+	ansiRed         = "\033[1;31m"
+	ansiGreen       = "\033[32m"
+	ansiReset       = "\033[0m"
+)
+
+// fileStateType is what changes of watched files and directories are noticed by
+type fileStateType struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// watchedPathsOf returns files of the packages and their directories, changing as files are added or removed
+func watchedPathsOf(pkgs []*packages.Package) []string {
+	seen := map[string]bool{}
+	paths := []string{}
+	for _, pkg := range pkgs {
+		for _, fn := range append(append([]string{}, pkg.GoFiles...), pkg.CompiledGoFiles...) {
+			for _, p := range []string{fn, filepath.Dir(fn)} {
+				if !seen[p] {
+					seen[p] = true
+					paths = append(paths, p)
+				}
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// watchedPathsOfArgs returns the .go files matching the args as in --syntax-only mode and their directories,
+// those of the current directory tree when none match, e.g. for import path patterns
+func watchedPathsOfArgs(args []string) []string {
+	fns := []string{}
+	for _, arg := range args {
+		if found, err := listSyntaxFiles([]string{arg}); err == nil {
+			fns = append(fns, found...)
+		}
+	}
+	if len(fns) == 0 {
+		fns, _ = listSyntaxFiles([]string{"./..."})
+	}
+	seen := map[string]bool{}
+	paths := []string{}
+	for _, fn := range fns {
+		fn, _ = filepath.Abs(fn)
+		for _, p := range []string{fn, filepath.Dir(fn)} {
+			if !seen[p] {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// statPaths returns states of the paths, missing ones are left out
+func statPaths(paths []string) map[string]fileStateType {
+	states := map[string]fileStateType{}
+	for _, p := range paths {
+		if fi, err := os.Stat(p); err == nil {
+			states[p] = fileStateType{ModTime: fi.ModTime(), Size: fi.Size()}
+		}
+	}
+	return states
+}
+
+func isSameStates(a map[string]fileStateType, b map[string]fileStateType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for p, s := range a {
+		if o, ok := b[p]; !ok || !o.ModTime.Equal(s.ModTime) || o.Size != s.Size {
+			return false
+		}
+	}
+	return true
+}
+
+// packageSignature changes whenever any of the package files changes
+func packageSignature(pkg *packages.Package, states map[string]fileStateType) string {
+	sb := strings.Builder{}
+	for _, fn := range pkg.CompiledGoFiles {
+		s := states[fn]
+		fmt.Fprintf(&sb, "%s %d %d\n", fn, s.ModTime.UnixNano(), s.Size)
+	}
+	return sb.String()
+}
+
+// watchSessionType keeps analysis results between refreshes
+type watchSessionType struct {
+	args     []string
+	analyzer *analysis.Analyzer
+	baseline *jsonReportTag                    // results when watching started
+	found    map[string]foundDiagnosticsStruct // by package ID
+	sigs     map[string]string                 // package signatures found was analyzed with
+	paths    []string
+	states   map[string]fileStateType
+}
+
+func newWatchSession(args []string, analyzer *analysis.Analyzer) *watchSessionType {
+	return &watchSessionType{
+		args:     args,
+		analyzer: analyzer,
+		found:    map[string]foundDiagnosticsStruct{},
+		sigs:     map[string]string{},
+		states:   map[string]fileStateType{},
+	}
+}
+
+// isChanged tells if any watched file or directory changed since last refresh,
+// always when no files are known because packages could not be listed
+func (s *watchSessionType) isChanged() bool {
+	return len(s.paths) == 0 || !isSameStates(s.states, statPaths(s.paths))
+}
+
+// refresh lists packages again and analyzes the ones with changed files, returning how many
+func (s *watchSessionType) refresh() (int, error) {
+	listed, ok := listPackages(s.args)
+	if !ok {
+		// watch what the args match, so the next refresh waits for a fix instead of the next interval
+		s.paths = watchedPathsOfArgs(s.args)
+		s.states = statPaths(s.paths)
+		if _, err := load(s.args, s.analyzer); err != nil { // to report the errors
+			return 0, err
+		}
+		return 0, fmt.Errorf("error during listing packages")
+	}
+	s.paths = watchedPathsOf(listed)
+	s.states = statPaths(s.paths)
+
+	affected := []*packages.Package{}
+	sigs := map[string]string{}
+	for _, pkg := range listed {
+		sigs[pkg.ID] = packageSignature(pkg, s.states)
+		if _, ok := s.found[pkg.ID]; !ok || s.sigs[pkg.ID] != sigs[pkg.ID] {
+			affected = append(affected, pkg)
+		}
+	}
+	analyzed := []foundDiagnosticsStruct{}
+	if len(affected) > 0 {
+		var err error
+		if analyzed, err = analyzeListed(s.args, affected, s.analyzer); err != nil {
+			return 0, err
+		}
+	}
+
+	found := map[string]foundDiagnosticsStruct{}
+	for _, pkg := range listed {
+		if f, ok := s.found[pkg.ID]; ok {
+			found[pkg.ID] = f
+		}
+	}
+	for _, f := range analyzed {
+		found[f.pkg.ID] = f
+	}
+	s.found, s.sigs = found, sigs
+	if s.baseline == nil {
+		report := toJSONReport(funcStatsOf(s.results()))
+		s.baseline = &report
+	}
+	return len(affected), nil
+}
+
+// results returns the latest results of all packages, ordered by package
+func (s *watchSessionType) results() []foundDiagnosticsStruct {
+	arr := []foundDiagnosticsStruct{}
+	for _, f := range s.found {
+		arr = append(arr, f)
+	}
+	sort.SliceStable(arr, func(i, j int) bool { return lessPackage(arr[i].pkg, arr[j].pkg) })
+	return arr
+}
+
+// doPrintWatch prints txt output with thresholds crossed since the baseline highlighted,
+// followed by thresholds fixed since then
+func doPrintWatch(w io.Writer, baseline *jsonReportTag, stats []complexity.FuncStatsType, arr []foundDiagnosticsStruct, color bool) error {
+	newViolations, fixedViolations := compareBaseline(baseline, stats)
+	isNew := map[string]bool{}
+	for _, v := range newViolations {
+		isNew[v.key()] = true
+	}
+	newLines := map[string]bool{} // by "file:line: message"
+	for _, s := range stats {
+		for _, v := range violationsOf(s) {
+			if isNew[baselineViolationType{QualifiedName: s.QualifiedName(), Metric: v.Metric}.key()] {
+				newLines[fmt.Sprintf("%s:%d: %s", getRelativeFileName(s.Filename, currDir), s.Line, v.Message)] = true
+			}
+		}
+	}
+	highlight := func(s string, esc string) string {
+		if color {
+			return esc + s + ansiReset
+		}
+		return s
+	}
+
+	bw := bufio.NewWriter(w)
+	lines := toTxtLines(arr)
+	for _, l := range lines {
+		line := fmt.Sprintf("%s:%d:%d: %s", l.File, l.Line, l.Column, l.Msg)
+		if newLines[fmt.Sprintf("%s:%d: %s", l.File, l.Line, l.Msg)] {
+			fmt.Fprintln(bw, highlight("+ "+line, ansiRed))
+		} else {
+			fmt.Fprintln(bw, "  "+line)
+		}
+	}
+	for _, v := range fixedViolations {
+		title := strings.ToLower(metrics[metricIndex(v.Metric)].Title)
+		fmt.Fprintln(bw, highlight(fmt.Sprintf("- %s:%d: fixed %s (%s was %d)", v.File, v.Line, v.QualifiedName, title, v.Value), ansiGreen))
+	}
+	if len(lines) > 0 || len(fixedViolations) > 0 {
+		fmt.Fprintln(bw)
+	}
+	fmt.Fprintf(bw, "%s %d new, %d fixed since watching started.\n", txtSummary(stats), len(newViolations), len(fixedViolations))
+	return bw.Flush()
+}
+
+// isTerminal tells if the file is a character device, e.g. not redirected to a file or pipe
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// runWatch implements "complexity watch [-interval 1s] [package]", running until interrupted
+func runWatch(args []string, analyzer *analysis.Analyzer) (exitcode int) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	interval := fs.Duration("interval", time.Second, "how often to check files for modifications")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s watch [-flag] [package]\n\nFlags:\n", analyzer.Name)
+		fs.PrintDefaults()
+	}
+	patterns, err := parseInterspersed(fs, args)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	if len(patterns) == 0 || *interval <= 0 {
+		fs.Usage()
+		return 1
+	}
+
+	color := isTerminal(os.Stdout)
+	s := newWatchSession(patterns, analyzer)
+	for {
+		if color {
+			fmt.Fprint(os.Stdout, ansiClearScreen)
+		}
+		start := time.Now()
+		n, err := s.refresh()
+		if err != nil {
+			log.Print(err)
+		} else {
+			fmt.Fprintf(os.Stdout, "%s: %d packages analyzed in %s\n\n", start.Format("15:04:05"), n, time.Since(start).Round(time.Millisecond))
+			arr := s.results()
+			if err := doPrintWatch(os.Stdout, s.baseline, funcStatsOf(arr), arr, color); err != nil {
+				log.Print(err)
+				return 1
+			}
+		}
+		fmt.Fprintf(os.Stdout, "\nwatching %d files and directories for changes, ^C to stop\n", len(s.paths))
+		for changed := false; !changed; changed = s.isChanged() {
+			time.Sleep(*interval)
+		}
+	}
+}
@@ -1,12 +1,218 @@
 package complexity
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
 )
 
 // TestAnalyzer is a test for Analyzer.
 func TestAnalyzer(t *testing.T) {
 	analysistest.Run(t, analysistest.TestData(), Analyzer, []string{"a", "halstead"}...)
 }
+
+// TestGoldenMetrics pins the metrics of the testdata functions to the values computed
+// by the former walkers, one per metric, before they were merged into a single traversal.
+func TestGoldenMetrics(t *testing.T) {
+	type metricsType struct {
+		cyclo, loc, maint  int
+		difficulty, volume float64
+	}
+	expected := map[string]metricsType{
+		"a.f0":              {1, 2, 86, 0, 8},
+		"a.f1":              {3, 7, 71, 0, 25.26619429851844},
+		"a.f2":              {8, 20, 55, 10.833333333333334, 144},
+		"a.f3":              {4, 7, 70, 0, 36},
+		"a.f4":              {2, 15, 59, 4.136363636363637, 125.09775004326937},
+		"a.f5":              {1, 7, 70, 3.75, 39.86313713864835},
+		"halstead.f1":       {1, 3, 80, 2.5, 18.094737505048094},
+		"halstead.f2":       {1, 7, 67, 6.857142857142857, 101.57915548582149},
+		"halstead.f3":       {3, 7, 71, 0, 25.26619429851844},
+		"halstead.f4":       {8, 20, 55, 10.833333333333334, 144},
+		"halstead.(*t1).f5": {1, 2, 86, 0, 10},
+		"halstead.comp1":    {1, 5, 73, 7, 38.03910001730775},
+		"halstead.comp2":    {1, 5, 73, 3.5, 41.20902501875006},
+		"halstead.comp3":    {3, 4, 75, 3.5, 41.20902501875006},
+		"halstead.comp4":    {5, 7, 66, 12, 101.57915548582149},
+		"halstead.comp5":    {1, 4, 76, 3.5, 27},
+		"halstead.comp6":    {4, 11, 62, 7.5, 92},
+		"halstead.comp7":    {4, 10, 64, 9, 88},
+		"halstead.comp8":    {2, 6, 69, 6.3, 76.14709844115208},
+	}
+	found := 0
+	for _, r := range analysistest.Run(t, analysistest.TestData(), Analyzer, "a", "halstead") {
+		for _, s := range r.Result.([]FuncStatsType) {
+			name := s.QualifiedName()
+			e, ok := expected[name]
+			if !ok {
+				t.Errorf("%s: unexpected function", name)
+				continue
+			}
+			found++
+			got := metricsType{s.CyclomaticComplexity, s.LOC, s.MaintenabilityIndex, s.HalsbreadDifficulty, s.HalsbreadVolume}
+			if got.cyclo != e.cyclo || got.loc != e.loc || got.maint != e.maint ||
+				math.Abs(got.difficulty-e.difficulty) > 1e-9 || math.Abs(got.volume-e.volume) > 1e-9 {
+				t.Errorf("%s: expected %+v, got %+v", name, e, got)
+			}
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("expected %d functions, found %d", len(expected), found)
+	}
+}
+
+func TestQualifiedName(t *testing.T) {
+	for _, tc := range []struct {
+		stats    FuncStatsType
+		expected string
+	}{
+		{FuncStatsType{PackagePath: "example.com/a", FunctionName: "f"}, "example.com/a.f"},
+		{FuncStatsType{PackagePath: "example.com/a", Receiver: "T", FunctionName: "f"}, "example.com/a.T.f"},
+		{FuncStatsType{PackagePath: "example.com/a", Receiver: "*T", FunctionName: "f"}, "example.com/a.(*T).f"},
+		{FuncStatsType{Receiver: "*T", FunctionName: "f"}, "(*T).f"},
+	} {
+		if got := tc.stats.QualifiedName(); got != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, got)
+		}
+		if got, expected := tc.stats.LocalName(), strings.TrimPrefix(tc.expected, "example.com/a."); got != expected {
+			t.Errorf("expected local name %q, got %q", expected, got)
+		}
+	}
+}
+
+func TestLineStats(t *testing.T) {
+	src := `package a
+
+func f(ch chan int, a, b bool) int {
+	if a && b {
+		ch <- 1
+	} else {
+		go func() {}()
+	}
+	return <-ch
+}
+`
+	fs := token.NewFileSet()
+	file, err := parser.ParseFile(fs, "a.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := file.Decls[0].(*ast.FuncDecl)
+
+	c := collectFuncMetrics(fs, fd, true)
+	cyclo := map[int]int{}
+	sumOpt, sumOpd := 0, 0
+	for _, l := range c.lineStats() {
+		cyclo[l.Line] = l.Cyclomatic
+		sumOpt += l.Operators
+		sumOpd += l.Operands
+	}
+	expected := map[int]int{3: 1, 4: 2, 5: 1, 6: 1, 7: 2, 9: 1}
+	for line, inc := range expected {
+		if cyclo[line] != inc {
+			t.Errorf("line %d: expected cyclomatic %d, got %d", line, inc, cyclo[line])
+		}
+	}
+
+	expOpt, expOpd := 0, 0
+	for _, v := range c.halst.opt {
+		expOpt += v
+	}
+	for _, v := range c.halst.opd {
+		expOpd += v
+	}
+	if sumOpt != expOpt || sumOpd != expOpd {
+		t.Errorf("expected %d operators and %d operands, got %d and %d", expOpt, expOpd, sumOpt, sumOpd)
+	}
+	total := 0
+	for _, v := range cyclo {
+		total += v
+	}
+	if total != c.cyclo {
+		t.Errorf("expected cyclomatic complexity %d, got %d", c.cyclo, total)
+	}
+}
+
+func TestCognitiveComplexity(t *testing.T) {
+	for _, tc := range []struct {
+		src      string
+		expected int
+	}{
+		{"func f() {}", 0},
+		{"func f(a, b, c bool) { if a && b && c {} }", 2},
+		{"func f(a, b, c bool) { if a && b || c {} }", 3},
+		{"func f(a, b bool) { if a {} else if b {} else {} }", 3},
+		{"func f(a, b bool) { for a { if b { switch {} } } }", 6},
+		{"func f(ch chan int) { go func() { select { case <-ch: } }() }", 2},
+		{"func f(x any) { switch x.(type) { case int: if x != nil {} } }", 3},
+		{"func f() { L: for { for { continue L } } }", 4},
+		{"func f(n int) int { if n > 0 { return f(n-1) }; return 0 }", 2},
+		{"func (t *T) f(n int) { t.f(n); f(n) }", 1},
+	} {
+		fs := token.NewFileSet()
+		file, err := parser.ParseFile(fs, "a.go", "package a\n\n"+tc.src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd := file.Decls[0].(*ast.FuncDecl)
+		c := collectFuncMetrics(fs, fd, true)
+		if c.cognitive != tc.expected {
+			t.Errorf("%s: expected cognitive complexity %d, got %d", tc.src, tc.expected, c.cognitive)
+		}
+		total := 0
+		for _, l := range c.lineStats() {
+			total += l.Cognitive
+		}
+		if total != c.cognitive {
+			t.Errorf("%s: expected line contributions to sum up to %d, got %d", tc.src, c.cognitive, total)
+		}
+	}
+}
+
+// benchmarkAnalyzer runs the Analyzer over the sources of this repository
+func benchmarkAnalyzer(b *testing.B, withLineStats bool) {
+	filenames, err := filepath.Glob("cmd/complexity/*.go")
+	if err != nil {
+		b.Fatal(err)
+	}
+	fs := token.NewFileSet()
+	files := []*ast.File{}
+	for _, fn := range filenames {
+		f, err := parser.ParseFile(fs, fn, nil, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	defer func(v bool) { CollectLineStats = v }(CollectLineStats)
+	CollectLineStats = withLineStats
+
+	pass := &analysis.Pass{
+		Analyzer: Analyzer,
+		Fset:     fs,
+		Files:    files,
+		ResultOf: map[*analysis.Analyzer]interface{}{inspect.Analyzer: inspector.New(files)},
+		Report:   func(analysis.Diagnostic) {},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := runComp(pass); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAnalyzer(b *testing.B) { benchmarkAnalyzer(b, false) }
+
+func BenchmarkAnalyzerLineStats(b *testing.B) { benchmarkAnalyzer(b, true) }
@@ -1,16 +1,16 @@
 module github.com/fikin/go-complexity-analysis
 
-go 1.24.1
+go 1.25.0
 
 require (
 	github.com/stretchr/testify v1.7.0
-	golang.org/x/tools v0.31.0
+	golang.org/x/mod v0.35.0
+	golang.org/x/tools v0.44.0
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/mod v0.24.0 // indirect
-	golang.org/x/sync v0.12.0 // indirect
+	golang.org/x/sync v0.20.0 // indirect
 )
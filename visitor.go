@@ -0,0 +1,431 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// funcMetricsCollector gathers all metrics of a function in one preorder traversal.
+// It is fed by push and pop of every node of the function, starting with the *ast.FuncDecl,
+// and can be reused for the next function once all nodes are popped.
+//
+// Halstead operators and operands are counted only along the edges listed in halstEdgeWeight,
+// each node contributing its own tokens only. Other metrics look at every node.
+type funcMetricsCollector struct {
+	fs            *token.FileSet
+	withLineStats bool
+	fd            *ast.FuncDecl
+	cyclo         int
+	cognitive     int
+	varsLOC       int
+	halst         *halstCounter
+	stack         []stackEntry
+	lines         map[int]*LineStatsType
+}
+
+// stackEntry is a node being traversed with its Halstead weight, 0 when not counted,
+// and the nesting level of its statements for Cognitive complexity
+type stackEntry struct {
+	node    ast.Node
+	weight  int
+	nesting int
+}
+
+func newFuncMetricsCollector(fs *token.FileSet, withLineStats bool) *funcMetricsCollector {
+	c := &funcMetricsCollector{fs: fs, withLineStats: withLineStats, halst: newHalstCounter(), stack: make([]stackEntry, 0, 32)}
+	if withLineStats {
+		c.halst.onCount = func(pos token.Pos, isOperator bool, count int) {
+			if isOperator {
+				c.at(pos).Operators += count
+			} else {
+				c.at(pos).Operands += count
+			}
+		}
+	}
+	return c
+}
+
+// collectFuncMetrics traverses the function on its own, without an inspector
+func collectFuncMetrics(fs *token.FileSet, fd *ast.FuncDecl, withLineStats bool) *funcMetricsCollector {
+	c := newFuncMetricsCollector(fs, withLineStats)
+	ast.Inspect(fd, func(n ast.Node) bool {
+		switch {
+		case n == fd:
+			c.start(fd)
+		case n == nil:
+			c.pop()
+		default:
+			c.push(n)
+		}
+		return true
+	})
+	return c
+}
+
+// start resets the metrics and pushes the function
+func (c *funcMetricsCollector) start(fd *ast.FuncDecl) {
+	c.fd = fd
+	c.cyclo = 1
+	c.cognitive = 0
+	c.varsLOC = 0
+	c.halst.reset()
+	c.lines = nil
+	if c.withLineStats {
+		c.lines = map[int]*LineStatsType{}
+		c.at(fd.Pos()).Cyclomatic++ // the initial Cyclomatic complexity of 1 is attributed to the func line
+	}
+	c.push(fd)
+}
+
+// isDone tells if all nodes pushed are popped
+func (c *funcMetricsCollector) isDone() bool {
+	return len(c.stack) == 0
+}
+
+// at returns the line stats of the position's line, func line for invalid positions
+func (c *funcMetricsCollector) at(pos token.Pos) *LineStatsType {
+	if !pos.IsValid() {
+		pos = c.fd.Pos()
+	}
+	line := c.fs.Position(pos).Line
+	l, ok := c.lines[line]
+	if !ok {
+		l = &LineStatsType{Line: line}
+		c.lines[line] = l
+	}
+	return l
+}
+
+func (c *funcMetricsCollector) push(n ast.Node) {
+	var parent ast.Node
+	weight, nesting := 1, 0
+	if len(c.stack) > 0 {
+		top := c.stack[len(c.stack)-1]
+		parent = top.node
+		weight = top.weight * halstEdgeWeight(parent, n)
+		nesting = top.nesting
+	}
+	c.stack = append(c.stack, stackEntry{n, weight, nesting + nestingOf(parent, n)})
+
+	if pos, inc := cycloOf(parent, n); inc > 0 {
+		c.cyclo += inc
+		if c.lines != nil {
+			c.at(pos).Cyclomatic += inc
+		}
+	}
+	if pos, inc := cognitiveOf(c.fd, parent, n, nesting); inc > 0 {
+		c.cognitive += inc
+		if c.lines != nil {
+			c.at(pos).Cognitive += inc
+		}
+	}
+	c.varsLOC += varsLOCOf(c.fs, n)
+	if weight > 0 {
+		c.halst.weight = weight
+		halstOf(n, c.halst)
+	}
+}
+
+func (c *funcMetricsCollector) pop() {
+	c.stack = c.stack[:len(c.stack)-1]
+}
+
+// lineStats returns per line contributions ordered by line, nil unless collected
+func (c *funcMetricsCollector) lineStats() []LineStatsType {
+	if c.lines == nil {
+		return nil
+	}
+	arr := make([]LineStatsType, 0, len(c.lines))
+	for _, l := range c.lines {
+		arr = append(arr, *l)
+	}
+	sort.Slice(arr, func(i, j int) bool { return arr[i].Line < arr[j].Line })
+	return arr
+}
+
+// cycloOf returns the increase of Cyclomatic complexity by the node and where the branch is, if any
+func cycloOf(parent ast.Node, n ast.Node) (token.Pos, int) {
+	switch n := n.(type) {
+	case *ast.GoStmt: // subroutines are double complexity
+		return n.Go, 2
+	case *ast.SendStmt: // writing to channels
+		return n.Arrow, 1
+	case *ast.UnaryExpr:
+		if n.Op == token.ARROW { // channel reading
+			return n.OpPos, 1
+		}
+	case *ast.IfStmt:
+		return n.If, 1
+	case *ast.BlockStmt:
+		if p, ok := parent.(*ast.IfStmt); ok && p.Else == ast.Stmt(n) { // include final else
+			return n.Lbrace, 1
+		}
+	case *ast.ForStmt, *ast.RangeStmt, *ast.SelectStmt, *ast.SwitchStmt:
+		return n.Pos(), 1
+	case *ast.BinaryExpr:
+		if n.Op == token.LAND || n.Op == token.LOR {
+			return n.OpPos, 1
+		}
+	}
+	return token.NoPos, 0
+}
+
+// nestingOf returns 1 if the node is a body the parent nests its statements in, 0 otherwise.
+// Bodies of else-if chains are nested as deep as the first if.
+func nestingOf(parent ast.Node, n ast.Node) int {
+	var body ast.Node
+	switch p := parent.(type) {
+	case *ast.IfStmt:
+		if b, ok := p.Else.(*ast.BlockStmt); ok && n == ast.Node(b) {
+			return 1
+		}
+		body = p.Body
+	case *ast.ForStmt:
+		body = p.Body
+	case *ast.RangeStmt:
+		body = p.Body
+	case *ast.SwitchStmt:
+		body = p.Body
+	case *ast.TypeSwitchStmt:
+		body = p.Body
+	case *ast.SelectStmt:
+		body = p.Body
+	case *ast.FuncLit:
+		body = p.Body
+	}
+	if body != nil && n == body {
+		return 1
+	}
+	return 0
+}
+
+// cognitiveOf returns the increase of Cognitive complexity by the node nested at the given level and where it is, if any
+func cognitiveOf(fd *ast.FuncDecl, parent ast.Node, n ast.Node, nesting int) (token.Pos, int) {
+	switch n := n.(type) {
+	case *ast.IfStmt:
+		if p, ok := parent.(*ast.IfStmt); ok && p.Else == ast.Stmt(n) { // else if
+			return n.If, 1
+		}
+		return n.If, 1 + nesting
+	case *ast.BlockStmt:
+		if p, ok := parent.(*ast.IfStmt); ok && p.Else == ast.Stmt(n) { // final else
+			return n.Lbrace, 1
+		}
+	case *ast.ForStmt, *ast.RangeStmt, *ast.SelectStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt:
+		return n.Pos(), 1 + nesting
+	case *ast.BranchStmt:
+		if n.Tok == token.GOTO || n.Label != nil { // goto and break or continue to a label
+			return n.TokPos, 1
+		}
+	case *ast.BinaryExpr:
+		if n.Op != token.LAND && n.Op != token.LOR {
+			break
+		}
+		if p, ok := parent.(*ast.BinaryExpr); ok && p.Op == n.Op { // sequence of the same operator
+			break
+		}
+		return n.OpPos, 1
+	case *ast.CallExpr:
+		if isRecursiveCall(fd, n) {
+			return n.Lparen, 1
+		}
+	}
+	return token.NoPos, 0
+}
+
+// isRecursiveCall tells if the call is a direct call of the function, e.g. "f()" or "r.m()" in the method m of receiver r
+func isRecursiveCall(fd *ast.FuncDecl, call *ast.CallExpr) bool {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fd.Recv == nil && fun.Name == fd.Name.Name
+	case *ast.SelectorExpr:
+		if fd.Recv == nil || len(fd.Recv.List) == 0 || len(fd.Recv.List[0].Names) == 0 || fun.Sel.Name != fd.Name.Name {
+			return false
+		}
+		x, ok := fun.X.(*ast.Ident)
+		return ok && x.Name == fd.Recv.List[0].Names[0].Name
+	}
+	return false
+}
+
+// varsLOCOf returns lines of the node if it declares variables or constants
+func varsLOCOf(fs *token.FileSet, n ast.Node) int {
+	switch nn := n.(type) {
+	case *ast.ValueSpec:
+		return countLOC(fs, n)
+	case *ast.AssignStmt:
+		if nn.Tok == token.DEFINE { // variable declaration & assignment
+			return countLOC(fs, n)
+		}
+	}
+	return 0
+}
+
+// halstEdgeWeight tells how many times Halstead counting descends from the parent into the child,
+// 0 for children not counted, e.g. function parameter names, results and receivers or struct fields.
+func halstEdgeWeight(parent ast.Node, child ast.Node) int {
+	switch p := parent.(type) {
+	case *ast.FuncDecl:
+		if child == ast.Node(p.Body) {
+			return 1
+		}
+	case *ast.ValueSpec:
+		for _, n := range p.Names {
+			if child == ast.Node(n) {
+				return 1
+			}
+		}
+		return len(p.Names) // type and values are counted for each name
+	case *ast.FuncType:
+		if child == ast.Node(p.Params) {
+			return 1
+		}
+	case *ast.Field:
+		if child == ast.Node(p.Type) {
+			return 1
+		}
+	case *ast.FieldList,
+		*ast.GenDecl, *ast.DeclStmt, *ast.ExprStmt, *ast.SendStmt, *ast.IncDecStmt, *ast.AssignStmt,
+		*ast.GoStmt, *ast.DeferStmt, *ast.ReturnStmt, *ast.BranchStmt, *ast.BlockStmt, *ast.IfStmt,
+		*ast.SwitchStmt, *ast.SelectStmt, *ast.ForStmt, *ast.RangeStmt, *ast.CaseClause,
+		*ast.ParenExpr, *ast.SelectorExpr, *ast.IndexExpr, *ast.SliceExpr, *ast.TypeAssertExpr,
+		*ast.CallExpr, *ast.StarExpr, *ast.UnaryExpr, *ast.BinaryExpr, *ast.KeyValueExpr,
+		*ast.FuncLit, *ast.CompositeLit, *ast.Ellipsis, *ast.ChanType:
+		return 1
+	}
+	return 0
+}
+
+// halstOf counts the node's own Halstead operators and operands, children being counted on their own
+func halstOf(n ast.Node, c *halstCounter) {
+	switch n := n.(type) {
+	case *ast.GenDecl:
+		c.appendValidSymb(n.Lparen, n.Rparen, "()")
+		c.token(n.TokPos, n.Tok)
+	case *ast.FuncDecl:
+		c.operator(n.Type.Func, "func")
+		c.operator(n.Name.Pos(), n.Name.Name)
+		c.operator(n.Type.Params.Opening, "()")
+		if n.Recv != nil {
+			c.operator(n.Recv.Opening, "()")
+		}
+	case *ast.SendStmt:
+		if n.Arrow.IsValid() {
+			c.operator(n.Arrow, "<-")
+		}
+	case *ast.IncDecStmt:
+		if n.Tok.IsOperator() {
+			c.operator(n.TokPos, n.Tok.String())
+		}
+	case *ast.AssignStmt:
+		if n.Tok.IsOperator() {
+			c.operator(n.TokPos, n.Tok.String())
+		}
+	case *ast.GoStmt:
+		if n.Go.IsValid() {
+			c.operator(n.Go, "go")
+		}
+	case *ast.DeferStmt:
+		if n.Defer.IsValid() {
+			c.operator(n.Defer, "defer")
+		}
+	case *ast.ReturnStmt:
+		if n.Return.IsValid() {
+			c.operator(n.Return, "return")
+		}
+	case *ast.BranchStmt:
+		c.token(n.TokPos, n.Tok)
+	case *ast.BlockStmt:
+		c.appendValidSymb(n.Lbrace, n.Rbrace, "{}")
+	case *ast.IfStmt:
+		if n.If.IsValid() {
+			c.operator(n.If, "if")
+		}
+		if n.Else != nil {
+			c.operator(n.Else.Pos(), "else")
+		}
+	case *ast.SwitchStmt:
+		if n.Switch.IsValid() {
+			c.operator(n.Switch, "switch")
+		}
+	case *ast.SelectStmt:
+		if n.Select.IsValid() {
+			c.operator(n.Select, "select")
+		}
+	case *ast.ForStmt:
+		if n.For.IsValid() {
+			c.operator(n.For, "for")
+		}
+	case *ast.RangeStmt:
+		if n.For.IsValid() {
+			c.operator(n.For, "for")
+		}
+		if n.Key != nil {
+			c.token(n.TokPos, n.Tok)
+		}
+		c.operator(n.Range, "range")
+	case *ast.CaseClause:
+		if n.List == nil {
+			c.operator(n.Case, "default")
+		}
+		if n.Colon.IsValid() {
+			c.operator(n.Colon, ":")
+		}
+	case *ast.ParenExpr:
+		c.appendValidSymb(n.Lparen, n.Rparen, "()")
+	case *ast.IndexExpr:
+		c.appendValidSymb(n.Lbrack, n.Rbrack, "{}")
+	case *ast.SliceExpr:
+		c.appendValidSymb(n.Lbrack, n.Rbrack, "[]")
+	case *ast.TypeAssertExpr:
+		c.appendValidSymb(n.Lparen, n.Rparen, "()")
+	case *ast.CallExpr:
+		c.appendValidSymb(n.Lparen, n.Rparen, "()")
+		if n.Ellipsis != 0 {
+			c.operator(n.Ellipsis, "...")
+		}
+	case *ast.StarExpr:
+		if n.Star.IsValid() {
+			c.operator(n.Star, "*")
+		}
+	case *ast.UnaryExpr:
+		c.token(n.OpPos, n.Op)
+	case *ast.BinaryExpr:
+		c.operator(n.OpPos, n.Op.String())
+	case *ast.KeyValueExpr:
+		if n.Colon.IsValid() {
+			c.operator(n.Colon, ":")
+		}
+	case *ast.BasicLit:
+		if n.Kind.IsLiteral() {
+			c.operand(n.ValuePos, n.Value)
+		} else {
+			c.operator(n.ValuePos, n.Value)
+		}
+	case *ast.CompositeLit:
+		c.appendValidSymb(n.Lbrace, n.Rbrace, "{}")
+	case *ast.Ident:
+		if n.Obj == nil {
+			c.operator(n.NamePos, n.Name)
+		} else {
+			c.operand(n.NamePos, n.Name)
+		}
+	case *ast.Ellipsis:
+		if n.Ellipsis.IsValid() {
+			c.operator(n.Ellipsis, "...")
+		}
+	case *ast.FuncType:
+		if n.Func.IsValid() {
+			c.operator(n.Func, "func")
+		}
+		c.operator(n.Pos(), "()")
+	case *ast.ChanType:
+		if n.Begin.IsValid() {
+			c.operator(n.Begin, "chan")
+		}
+		if n.Arrow.IsValid() {
+			c.operator(n.Arrow, "<-")
+		}
+	}
+}